@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+// terminalApprover asks for approval of a story on the terminal after
+// showing its commits and diff. Rejections ask for feedback for the agent.
+type terminalApprover struct {
+	in  io.Reader
+	out io.Writer
+
+	once  sync.Once
+	lines chan string
+}
+
+func newTerminalApprover(in io.Reader, out io.Writer) *terminalApprover {
+	return &terminalApprover{in: in, out: out, lines: make(chan string)}
+}
+
+func (a *terminalApprover) Approve(ctx context.Context, req ralph.ApprovalRequest) (ralph.Approval, error) {
+	fmt.Fprintln(a.out)
+	fmt.Fprintln(a.out, "===============================================================")
+	fmt.Fprintf(a.out, "  Story %s needs approval - %s\n", req.Story.ID, req.Story.Title)
+	fmt.Fprintln(a.out, "===============================================================")
+	if len(req.Commits) > 0 {
+		fmt.Fprintln(a.out, "Commits:")
+		for _, commit := range req.Commits {
+			fmt.Fprintf(a.out, "  %s %s\n", ralph.ShortHash(commit.Hash), commit.Subject)
+		}
+		fmt.Fprintln(a.out)
+	}
+	if req.Diff != "" {
+		fmt.Fprintln(a.out, req.Diff)
+		fmt.Fprintln(a.out)
+	} else {
+		fmt.Fprintln(a.out, "The iteration changed no committed files.")
+	}
+
+	for {
+		fmt.Fprintf(a.out, "Approve story %s? [y/n] ", req.Story.ID)
+		answer, err := a.readLine(ctx)
+		if err != nil {
+			return ralph.Approval{}, err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return ralph.Approval{Approved: true}, nil
+		case "n", "no":
+			fmt.Fprintln(a.out, "Feedback for the agent, end with an empty line:")
+			var feedback []string
+			for {
+				line, err := a.readLine(ctx)
+				if err != nil {
+					return ralph.Approval{}, err
+				}
+				if strings.TrimSpace(line) == "" {
+					break
+				}
+				feedback = append(feedback, line)
+			}
+			return ralph.Approval{Feedback: strings.Join(feedback, "\n")}, nil
+		}
+	}
+}
+
+// readLine waits for the next line of input. Input is read in the background
+// so that Ctrl+C isn't held up by a pending read.
+func (a *terminalApprover) readLine(ctx context.Context) (string, error) {
+	a.once.Do(func() {
+		go func() {
+			scanner := bufio.NewScanner(a.in)
+			for scanner.Scan() {
+				a.lines <- scanner.Text()
+			}
+			close(a.lines)
+		}()
+	})
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case line, ok := <-a.lines:
+		if !ok {
+			return "", errors.New("no answer on stdin, approving stories needs an interactive terminal")
+		}
+		return line, nil
+	}
+}
+
+// chanReader reads the chunks sent on a channel.
+type chanReader struct {
+	ch  chan []byte
+	buf []byte
+}
+
+func (r *chanReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		r.buf = <-r.ch
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+func TestTerminalApprover(t *testing.T) {
+	req := ralph.ApprovalRequest{
+		Story:   ralph.UserStory{ID: "US-002", Title: "Add login"},
+		Commits: []ralph.GitCommit{{Hash: "abc1234def", Subject: "feat: US-002 login"}},
+		Diff:    "+func login() {}",
+	}
+
+	t.Run("approve", func(t *testing.T) {
+		var out bytes.Buffer
+		approval, err := newTerminalApprover(strings.NewReader("maybe\nY\n"), &out).Approve(context.Background(), req)
+		if err != nil || !approval.Approved {
+			t.Fatalf("Expected approval, got %+v, %v", approval, err)
+		}
+		for _, want := range []string{"Story US-002 needs approval - Add login", "abc1234 feat: US-002 login", "+func login() {}", "Approve story US-002? [y/n] Approve story US-002?"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("reject with feedback", func(t *testing.T) {
+		approval, err := newTerminalApprover(strings.NewReader("n\nAdd tests\nfor errors\n\n"), io.Discard).Approve(context.Background(), req)
+		if err != nil || approval.Approved || approval.Feedback != "Add tests\nfor errors" {
+			t.Errorf("Expected rejection with feedback, got %+v, %v", approval, err)
+		}
+	})
+
+	t.Run("no input", func(t *testing.T) {
+		if _, err := newTerminalApprover(strings.NewReader(""), io.Discard).Approve(context.Background(), req); err == nil || !strings.Contains(err.Error(), "interactive terminal") {
+			t.Errorf("Expected an error without input, got %v", err)
+		}
+	})
+
+	t.Run("interrupted", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		in, _ := io.Pipe()
+		if _, err := newTerminalApprover(in, io.Discard).Approve(ctx, req); err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
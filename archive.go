@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+// runArchive implements `go-ralph archive`.
+func runArchive(args []string) {
+	fs := newFlagSet("archive [flags]", "Archive the current PRD and progress log to .ralph/archive/<date>-<branch> and start a fresh progress log.")
+	keepProgress := fs.Bool("keep-progress", false, "Keep the current progress log instead of starting a fresh one")
+	fs.Parse(args)
+
+	_, ralphDir := ralphDirs()
+	prdFile := filepath.Join(ralphDir, "prd.yaml")
+	progressFile := filepath.Join(ralphDir, "progress.txt")
+
+	if !fileExists(prdFile) && !fileExists(progressFile) {
+		fmt.Fprintf(os.Stderr, "Error: nothing to archive, .ralph/prd.yaml and .ralph/progress.txt not found\n")
+		os.Exit(1)
+	}
+
+	branch := getBranchFromPRD(prdFile)
+	if branch == "" {
+		branch = readFile(filepath.Join(ralphDir, ".last-branch"))
+	}
+	if branch == "" {
+		branch = "unnamed"
+	}
+
+	archiveFolder, err := ralph.Archive(ralphDir, branch)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating archive folder: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Archived %s to: %s\n", branch, archiveFolder)
+
+	if !*keepProgress {
+		ralph.InitProgressFile(progressFile)
+		fmt.Println("Started a fresh .ralph/progress.txt")
+	}
+}
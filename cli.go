@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type command struct {
+	name        string
+	summary     string
+	run         func(args []string)
+	subcommands string
+}
+
+var commands = []command{
+	{name: "init", summary: "Initialize .ralph with config, prompt and skills", run: runInitCommand},
+	{name: "run", summary: "Run the agent loop (default command)", run: runRun},
+	{name: "status", summary: "Show PRD progress, branch and lock state", run: runStatus},
+	{name: "validate", summary: "Validate the config and PRD without running", run: runValidate},
+	{name: "archive", summary: "Archive the current PRD and progress log", run: runArchive},
+	{name: "story", summary: "List, show and update PRD stories", run: runStory, subcommands: "list|show|pass|reset"},
+	{name: "config", summary: "Show the effective configuration", run: runConfig, subcommands: "show"},
+	{name: "logs", summary: "Show the journal of past runs", run: runLogs},
+	{name: "ctl", summary: "Pause, resume, skip stories of or stop the run in progress", run: runCtl, subcommands: "pause|resume|skip|stop-after-iteration|status"},
+	{name: "serve", summary: "Serve a web dashboard of the PRD and runs", run: runServe},
+	{name: "migrate", summary: "Upgrade config and PRD files to the current version", run: runMigrate},
+}
+
+func main() {
+	args := os.Args[1:]
+
+	if len(args) == 0 {
+		runRun(args)
+		return
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		printUsage()
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			cmd.run(args[1:])
+			return
+		}
+	}
+
+	// Backwards compatibility: `go-ralph --init --tool=x`
+	for i, arg := range args {
+		if arg == "-init" || arg == "--init" {
+			runInitCommand(append(args[:i:i], args[i+1:]...))
+			return
+		}
+	}
+
+	// Backwards compatibility: `go-ralph [flags]` and `go-ralph N` run the loop
+	if strings.HasPrefix(args[0], "-") {
+		runRun(args)
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err == nil {
+		runRun(args)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: unknown command '%s'\n\n", args[0])
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: go-ralph <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		name := cmd.name
+		if cmd.subcommands != "" {
+			name += " " + cmd.subcommands
+		}
+		fmt.Fprintf(os.Stderr, "  %-28s %s\n", name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\n'go-ralph' and 'go-ralph N' are shortcuts for 'go-ralph run'.\n")
+	fmt.Fprintf(os.Stderr, "Run 'go-ralph <command> --help' for the flags of a command.\n")
+}
+
+// newFlagSet returns a flag set whose help shows the command usage and
+// description above its flags.
+func newFlagSet(usage, description string) *flag.FlagSet {
+	name, _, _ := strings.Cut(usage, " ")
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go-ralph %s\n\n%s\n", usage, description)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(fs.Output(), "\nFlags:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// ralphDirs returns the working directory and its .ralph directory.
+func ralphDirs() (string, string) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting current directory: %v\n", err)
+		os.Exit(1)
+	}
+	return workDir, filepath.Join(workDir, ".ralph")
+}
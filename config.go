@@ -0,0 +1,471 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigLayer is one source of configuration values. Layers are applied in
+// order, later layers overriding earlier ones key by key.
+type ConfigLayer struct {
+	Name   string
+	Values map[string]any
+}
+
+// origin describes where key came from within the layer.
+func (l ConfigLayer) origin(key string) string {
+	switch l.Name {
+	case "env":
+		return "env RALPH_" + strings.ToUpper(key)
+	case "flag":
+		return "flag --" + strings.ReplaceAll(key, "_", "-")
+	}
+	return l.Name
+}
+
+// globalConfigPath follows the XDG base directory spec on every platform.
+func globalConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "go-ralph", "config.yaml")
+}
+
+// loadConfigLayer reads a YAML config file into a layer. Missing files yield
+// an empty layer.
+func loadConfigLayer(name, path string) (ConfigLayer, error) {
+	layer := ConfigLayer{Name: name}
+	if path == "" || !fileExists(path) {
+		return layer, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return layer, err
+	}
+	if err := yaml.Unmarshal(data, &layer.Values); err != nil {
+		return layer, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := checkConfigKeys(path, layer.Values); err != nil {
+		return layer, err
+	}
+	if profiles, ok := layer.Values["profiles"].(map[string]any); ok {
+		for _, name := range profileNames(profiles) {
+			values, _ := profiles[name].(map[string]any)
+			for _, key := range []string{"profiles", "version"} {
+				if _, ok := values[key]; ok {
+					return layer, fmt.Errorf("%s: profile '%s' can't set %s", path, name, key)
+				}
+			}
+			if err := checkConfigKeys(fmt.Sprintf("%s (profile %s)", path, name), values); err != nil {
+				return layer, err
+			}
+		}
+	}
+
+	return layer, nil
+}
+
+// configSections are the config keys holding a struct. Their keys are checked,
+// merged and shown one by one.
+var configSections = map[string]reflect.Type{
+	"hooks":         reflect.TypeOf(ralph.Hooks{}),
+	"notifications": reflect.TypeOf(ralph.Notifications{}),
+}
+
+// configKeys returns the YAML keys of Config in declaration order.
+func configKeys() []string {
+	return yamlKeys(reflect.TypeOf(ralph.Config{}))
+}
+
+// yamlKeys returns the YAML keys of the struct type t in declaration order.
+func yamlKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag != "" && tag != "-" {
+			keys = append(keys, tag)
+		}
+	}
+	return keys
+}
+
+// envConfigLayer reads RALPH_<KEY> variables for every scalar config key,
+// e.g. RALPH_MAX_ITERATIONS. Values are parsed as YAML scalars.
+func envConfigLayer() ConfigLayer {
+	layer := ConfigLayer{Name: "env", Values: map[string]any{}}
+	t := reflect.TypeOf(ralph.Config{})
+	for i := 0; i < t.NumField(); i++ {
+		kind := t.Field(i).Type.Kind()
+		if kind == reflect.Map || kind == reflect.Slice || kind == reflect.Struct {
+			continue
+		}
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if key == "version" {
+			continue
+		}
+		value, ok := os.LookupEnv("RALPH_" + strings.ToUpper(key))
+		if !ok || value == "" {
+			continue
+		}
+		var parsed any
+		if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+			parsed = value
+		}
+		layer.Values[key] = parsed
+	}
+	return layer
+}
+
+// configFileLayers returns the config file layers in precedence order:
+// global, repo and local.
+func configFileLayers(ralphDir string) ([]ConfigLayer, error) {
+	var layers []ConfigLayer
+
+	for _, source := range []struct{ name, path string }{
+		{"global " + globalConfigPath(), globalConfigPath()},
+		{"repo .ralph/config.yaml", filepath.Join(ralphDir, "config.yaml")},
+		{"local .ralph/config.local.yaml", filepath.Join(ralphDir, "config.local.yaml")},
+	} {
+		layer, err := loadConfigLayer(source.name, source.path)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+
+	return layers, nil
+}
+
+// mergeConfigLayers applies layers in order and returns the merged values
+// with the layer each key came from. tool_args is merged per tool, profiles
+// per profile name and config sections per key.
+func mergeConfigLayers(layers []ConfigLayer) (map[string]any, map[string]string) {
+	merged := map[string]any{}
+	origins := map[string]string{}
+
+	for _, layer := range layers {
+		for key, value := range layer.Values {
+			if _, ok := configSections[key]; ok || key == "tool_args" || key == "profiles" {
+				entries, _ := merged[key].(map[string]any)
+				if entries == nil {
+					entries = map[string]any{}
+				}
+				if values, ok := value.(map[string]any); ok {
+					for name, entry := range values {
+						entries[name] = entry
+						origins[key+"."+name] = layer.origin(key)
+					}
+				}
+				merged[key] = entries
+				continue
+			}
+			merged[key] = value
+			origins[key] = layer.origin(key)
+		}
+	}
+
+	return merged, origins
+}
+
+func configFromValues(values map[string]any) (*ralph.Config, error) {
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	var config ralph.Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// resolveConfig loads the effective configuration for ralphDir. The selected
+// profile (or RALPH_PROFILE when profile is empty) is applied on top of the
+// config files, below environment variables and flags.
+func resolveConfig(ralphDir, profile string, flags map[string]any) (*ralph.Config, map[string]string, error) {
+	layers, err := configFileLayers(ralphDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if profile == "" {
+		profile = os.Getenv("RALPH_PROFILE")
+	}
+	if profile != "" {
+		values, _ := mergeConfigLayers(layers)
+		profiles, _ := values["profiles"].(map[string]any)
+		profileValues, ok := profiles[profile].(map[string]any)
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown profile '%s' (available: %s)", profile, strings.Join(profileNames(profiles), ", "))
+		}
+		layers = append(layers, ConfigLayer{Name: "profile " + profile, Values: profileValues})
+	}
+
+	layers = append(layers, envConfigLayer())
+	layers = append(layers, ConfigLayer{Name: "flag", Values: flags})
+
+	values, origins := mergeConfigLayers(layers)
+	config, err := configFromValues(values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return config, origins, nil
+}
+
+func profileNames[T any](profiles map[string]T) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runConfig implements `go-ralph config [show] [--origin]`.
+func runConfig(args []string) {
+	if len(args) > 0 && args[0] == "show" {
+		args = args[1:]
+	}
+
+	fs := newFlagSet("config [show] [flags]", "Show the effective configuration merged from the global, repo and local config files, RALPH_* variables and the selected profile.")
+	showOrigin := fs.Bool("origin", false, "Show where each value comes from")
+	profile := fs.String("profile", "", "Config profile to show (overrides RALPH_PROFILE)")
+	fs.Parse(args)
+
+	_, ralphDir := ralphDirs()
+	config, origins, err := resolveConfig(ralphDir, *profile, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	printConfig(os.Stdout, config, origins, *showOrigin)
+
+	warnings, err := validateConfig(config, ralphDir)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config:\n%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printConfig(w io.Writer, config *ralph.Config, origins map[string]string, showOrigin bool) {
+	data, _ := yaml.Marshal(config)
+	var values map[string]any
+	yaml.Unmarshal(data, &values)
+
+	line := func(key string, value any) {
+		text := fmt.Sprintf("%s: %s", key, formatConfigValue(value))
+		if showOrigin {
+			origin := origins[key]
+			if origin == "" {
+				origin = "default"
+			}
+			text = fmt.Sprintf("%-40s # %s", text, origin)
+		}
+		fmt.Fprintln(w, text)
+	}
+
+	for _, key := range configKeys() {
+		if key == "profiles" || key == "version" {
+			continue
+		}
+		if _, ok := configSections[key]; ok || key == "tool_args" {
+			entries, _ := values[key].(map[string]any)
+			names := make([]string, 0, len(entries))
+			for name := range entries {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				value := entries[name]
+				if key == "notifications" && name == "webhooks" {
+					// Only the URLs, the rest may hold secrets
+					var urls []any
+					for _, hook := range config.Notifications.Webhooks {
+						urls = append(urls, hook.URL)
+					}
+					value = urls
+				}
+				line(key+"."+name, value)
+			}
+			continue
+		}
+		line(key, values[key])
+	}
+
+	if len(config.Profiles) > 0 {
+		fmt.Fprintf(w, "\nprofiles: %s\n", strings.Join(profileNames(config.Profiles), ", "))
+	}
+}
+
+func formatConfigValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprintf("%q", fmt.Sprint(item))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+var knownTools = []string{"claude", "copilot"}
+
+func isKnownTool(tool string) bool {
+	for _, known := range knownTools {
+		if tool == known {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConfigKeys rejects keys Config doesn't know about, suggesting the
+// closest known key for typos. Keys inside config sections are checked too.
+func checkConfigKeys(source string, values map[string]any) error {
+	errs := unknownKeys(source, "", values, configKeys())
+	for key, t := range configSections {
+		if section, ok := values[key].(map[string]any); ok {
+			errs = append(errs, unknownKeys(source, key+".", section, yamlKeys(t))...)
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// validateConfig checks the effective configuration. Problems that would make
+// a run fail are returned as an error; the rest are returned as warnings.
+func validateConfig(config *ralph.Config, ralphDir string) ([]string, error) {
+	var errs []error
+	var warnings []string
+
+	switch {
+	case config.Tool == "":
+		errs = append(errs, fmt.Errorf("tool is required (one of: %s)", strings.Join(knownTools, ", ")))
+	case !isKnownTool(config.Tool):
+		msg := fmt.Sprintf("unknown tool '%s' (one of: %s)", config.Tool, strings.Join(knownTools, ", "))
+		if suggestion := closestMatch(config.Tool, knownTools); suggestion != "" {
+			msg += fmt.Sprintf(", did you mean '%s'?", suggestion)
+		}
+		errs = append(errs, errors.New(msg))
+	}
+
+	if config.MaxIterations <= 0 {
+		errs = append(errs, fmt.Errorf("max_iterations must be greater than 0, got %d", config.MaxIterations))
+	}
+
+	if config.PromptFile == "" {
+		errs = append(errs, errors.New("prompt_file is required"))
+	} else if !fileExists(filepath.Join(ralphDir, config.ToolPromptFile())) {
+		errs = append(errs, fmt.Errorf("prompt_file '%s' not found in %s", config.ToolPromptFile(), ralphDir))
+	}
+
+	if config.Tool != "" {
+		if _, ok := config.ToolArgs[config.Tool]; !ok {
+			warnings = append(warnings, fmt.Sprintf("tool_args has no entry for '%s', it will run without arguments", config.Tool))
+		}
+	}
+
+	for i, hook := range config.Notifications.Webhooks {
+		if err := hook.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("notifications.webhooks[%d]: %w", i, err))
+		}
+	}
+
+	switch config.Hooks.PreIterationFailure {
+	case "", ralph.HookFailureAbort, ralph.HookFailureSkip:
+	default:
+		errs = append(errs, fmt.Errorf("hooks.pre_iteration_failure must be '%s' or '%s', got '%s'", ralph.HookFailureAbort, ralph.HookFailureSkip, config.Hooks.PreIterationFailure))
+	}
+
+	return warnings, errors.Join(errs...)
+}
+
+func unknownKeys(source, prefix string, values map[string]any, known []string) []error {
+	var errs []error
+	for key := range values {
+		if contains(known, key) {
+			continue
+		}
+		msg := fmt.Sprintf("%s: unknown key '%s%s'", source, prefix, key)
+		if suggestion := closestMatch(key, known); suggestion != "" {
+			msg += fmt.Sprintf(" (did you mean '%s%s'?)", prefix, suggestion)
+		}
+		errs = append(errs, errors.New(msg))
+	}
+	return errs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// closestMatch returns the candidate within two edits of value, if any.
+func closestMatch(value string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if d := editDistance(value, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Damerau-Levenshtein distance (with adjacent
+// transpositions) between a and b.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+func TestResolveConfig(t *testing.T) {
+	xdgDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdgDir)
+	t.Setenv("RALPH_TOOL", "")
+	t.Setenv("RALPH_MAX_ITERATIONS", "")
+
+	os.MkdirAll(filepath.Join(xdgDir, "go-ralph"), 0755)
+	os.WriteFile(filepath.Join(xdgDir, "go-ralph", "config.yaml"), []byte(`tool: copilot
+max_iterations: 3
+base_branch: develop
+tool_args:
+  claude:
+    - --global
+  copilot:
+    - --allow-all-tools
+hooks:
+  pre_run:
+    - docker compose up -d
+  on_failure:
+    - notify-send failed
+`), 0644)
+
+	ralphDir := t.TempDir()
+	os.WriteFile(filepath.Join(ralphDir, "config.yaml"), []byte(`tool: claude
+max_iterations: 10
+prompt_file: prompt.md
+tool_args:
+  claude:
+    - --print
+hooks:
+  pre_run:
+    - make seed
+`), 0644)
+	os.WriteFile(filepath.Join(ralphDir, "config.local.yaml"), []byte("auto_archive: true\n"), 0644)
+
+	t.Run("files override in order", func(t *testing.T) {
+		config, origins, err := resolveConfig(ralphDir, "", nil)
+		if err != nil {
+			t.Fatalf("resolveConfig failed: %v", err)
+		}
+
+		if config.Tool != "claude" || origins["tool"] != "repo .ralph/config.yaml" {
+			t.Errorf("Expected tool from repo config, got '%s' from '%s'", config.Tool, origins["tool"])
+		}
+		if config.BaseBranch != "develop" || !strings.HasPrefix(origins["base_branch"], "global ") {
+			t.Errorf("Expected base_branch from global config, got '%s' from '%s'", config.BaseBranch, origins["base_branch"])
+		}
+		if !config.AutoArchive || origins["auto_archive"] != "local .ralph/config.local.yaml" {
+			t.Errorf("Expected auto_archive from local config, got %v from '%s'", config.AutoArchive, origins["auto_archive"])
+		}
+		if len(config.ToolArgs["claude"]) != 1 || config.ToolArgs["claude"][0] != "--print" {
+			t.Errorf("Expected repo claude args, got %v", config.ToolArgs["claude"])
+		}
+		if len(config.ToolArgs["copilot"]) != 1 || !strings.HasPrefix(origins["tool_args.copilot"], "global ") {
+			t.Errorf("Expected global copilot args, got %v", config.ToolArgs["copilot"])
+		}
+		if len(config.Hooks.PreRun) != 1 || config.Hooks.PreRun[0] != "make seed" || origins["hooks.pre_run"] != "repo .ralph/config.yaml" {
+			t.Errorf("Expected repo pre_run hook, got %v from '%s'", config.Hooks.PreRun, origins["hooks.pre_run"])
+		}
+		if len(config.Hooks.OnFailure) != 1 || !strings.HasPrefix(origins["hooks.on_failure"], "global ") {
+			t.Errorf("Expected global on_failure hook, got %v", config.Hooks.OnFailure)
+		}
+	})
+
+	t.Run("env and flags override files", func(t *testing.T) {
+		t.Setenv("RALPH_TOOL", "copilot")
+		t.Setenv("RALPH_MAX_ITERATIONS", "20")
+
+		config, origins, err := resolveConfig(ralphDir, "", nil)
+		if err != nil {
+			t.Fatalf("resolveConfig failed: %v", err)
+		}
+		if config.Tool != "copilot" || origins["tool"] != "env RALPH_TOOL" {
+			t.Errorf("Expected tool from env, got '%s' from '%s'", config.Tool, origins["tool"])
+		}
+		if config.MaxIterations != 20 {
+			t.Errorf("Expected max_iterations 20 from env, got %d", config.MaxIterations)
+		}
+
+		config, origins, err = resolveConfig(ralphDir, "", map[string]any{"max_iterations": 30})
+		if err != nil {
+			t.Fatalf("resolveConfig failed: %v", err)
+		}
+		if config.MaxIterations != 30 || origins["max_iterations"] != "flag --max-iterations" {
+			t.Errorf("Expected max_iterations 30 from flag, got %d from '%s'", config.MaxIterations, origins["max_iterations"])
+		}
+	})
+}
+
+func TestPrintConfig(t *testing.T) {
+	config := &ralph.Config{
+		Tool:          "claude",
+		MaxIterations: 5,
+		ToolArgs:      map[string][]string{"claude": {"--print"}},
+		Hooks:         ralph.Hooks{PreRun: []string{"make seed"}},
+	}
+	config.Notifications.Bell = true
+	config.Notifications.Webhooks = []ralph.Webhook{{URL: "https://hooks.example.com/ralph", Secret: "s3cret"}}
+	origins := map[string]string{"tool": "env RALPH_TOOL"}
+
+	var out bytes.Buffer
+	printConfig(&out, config, origins, true)
+
+	text := out.String()
+	if !strings.Contains(text, "tool: claude") || !strings.Contains(text, "# env RALPH_TOOL") {
+		t.Errorf("Expected tool with origin, got:\n%s", text)
+	}
+	if !strings.Contains(text, "# default") {
+		t.Errorf("Expected unset values to show default origin, got:\n%s", text)
+	}
+	if !strings.Contains(text, `tool_args.claude: ["--print"]`) {
+		t.Errorf("Expected tool args, got:\n%s", text)
+	}
+	if !strings.Contains(text, `hooks.pre_run: ["make seed"]`) {
+		t.Errorf("Expected hooks, got:\n%s", text)
+	}
+	if !strings.Contains(text, "notifications.bell: true") {
+		t.Errorf("Expected notifications, got:\n%s", text)
+	}
+	if !strings.Contains(text, `notifications.webhooks: ["https://hooks.example.com/ralph"]`) || strings.Contains(text, "s3cret") {
+		t.Errorf("Expected webhook URLs only, got:\n%s", text)
+	}
+}
+
+func TestCheckConfigKeys(t *testing.T) {
+	err := checkConfigKeys("config.yaml", map[string]any{"tool": "claude", "max_iteration": 20})
+	if err == nil {
+		t.Fatal("Expected error for unknown key")
+	}
+	if !strings.Contains(err.Error(), "unknown key 'max_iteration'") || !strings.Contains(err.Error(), "did you mean 'max_iterations'?") {
+		t.Errorf("Expected suggestion in error, got: %v", err)
+	}
+
+	err = checkConfigKeys("config.yaml", map[string]any{"hooks": map[string]any{"pre_iteration": []any{"true"}, "post_iteraton": []any{"true"}}})
+	if err == nil || !strings.Contains(err.Error(), "unknown key 'hooks.post_iteraton' (did you mean 'hooks.post_iteration'?)") {
+		t.Errorf("Expected unknown hook error, got: %v", err)
+	}
+
+	if err := checkConfigKeys("config.yaml", map[string]any{"tool": "claude"}); err != nil {
+		t.Errorf("Expected no error for known keys, got: %v", err)
+	}
+}
+
+func TestLoadConfigLayerRejectsUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("tool: claude\nmax_iteration: 20\n"), 0644)
+
+	if _, err := loadConfigLayer("repo", path); err == nil {
+		t.Error("Expected error for unknown key")
+	}
+	if _, err := loadConfig(path); err == nil {
+		t.Error("Expected loadConfig to reject unknown key")
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	ralphDir := t.TempDir()
+	os.WriteFile(filepath.Join(ralphDir, "prompt.md"), []byte("prompt"), 0644)
+
+	t.Run("valid config", func(t *testing.T) {
+		config := &ralph.Config{Tool: "claude", MaxIterations: 5, PromptFile: "prompt.md", ToolArgs: map[string][]string{"claude": {"--print"}}}
+		warnings, err := validateConfig(config, ralphDir)
+		if err != nil {
+			t.Errorf("Expected valid config, got: %v", err)
+		}
+		if len(warnings) != 0 {
+			t.Errorf("Expected no warnings, got: %v", warnings)
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		config := &ralph.Config{Tool: "cluade", MaxIterations: 0, PromptFile: "missing.md", Hooks: ralph.Hooks{PreIterationFailure: "ignore"}, Notifications: ralph.Notifications{Webhooks: []ralph.Webhook{{Events: []ralph.EventType{"story_blocked"}}}}}
+		_, err := validateConfig(config, ralphDir)
+		if err == nil {
+			t.Fatal("Expected validation error")
+		}
+		for _, expected := range []string{"did you mean 'claude'?", "max_iterations must be greater than 0", "prompt_file 'missing.md' not found", "hooks.pre_iteration_failure must be 'abort' or 'skip'", "notifications.webhooks[0]: url is required", "unknown event 'story_blocked'"} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("Expected error to contain %q, got: %v", expected, err)
+			}
+		}
+	})
+
+	t.Run("warns about missing tool args", func(t *testing.T) {
+		config := &ralph.Config{Tool: "copilot", MaxIterations: 5, PromptFile: "prompt.md", ToolArgs: map[string][]string{"claude": {"--print"}}}
+		warnings, err := validateConfig(config, ralphDir)
+		if err != nil {
+			t.Errorf("Expected valid config, got: %v", err)
+		}
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "'copilot'") {
+			t.Errorf("Expected tool_args warning, got: %v", warnings)
+		}
+	})
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"claude", "claude", 0},
+		{"cluade", "claude", 1},
+		{"max_iteration", "max_iterations", 1},
+		{"copilot", "claude", 6},
+	}
+	for _, tt := range tests {
+		if d := editDistance(tt.a, tt.b); d != tt.expected {
+			t.Errorf("editDistance(%q, %q) = %d, expected %d", tt.a, tt.b, d, tt.expected)
+		}
+	}
+}
+
+func TestResolveConfigProfiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("RALPH_PROFILE", "")
+	t.Setenv("RALPH_MAX_ITERATIONS", "")
+
+	ralphDir := t.TempDir()
+	os.WriteFile(filepath.Join(ralphDir, "config.yaml"), []byte(`tool: copilot
+max_iterations: 5
+profiles:
+  overnight:
+    tool: claude
+    max_iterations: 50
+  cautious:
+    max_iterations: 3
+`), 0644)
+
+	t.Run("no profile", func(t *testing.T) {
+		config, _, err := resolveConfig(ralphDir, "", nil)
+		if err != nil {
+			t.Fatalf("resolveConfig failed: %v", err)
+		}
+		if config.Tool != "copilot" || config.MaxIterations != 5 {
+			t.Errorf("Expected top-level values, got %s/%d", config.Tool, config.MaxIterations)
+		}
+		if len(config.Profiles) != 2 {
+			t.Errorf("Expected 2 profiles, got %d", len(config.Profiles))
+		}
+	})
+
+	t.Run("profile flag", func(t *testing.T) {
+		config, origins, err := resolveConfig(ralphDir, "overnight", nil)
+		if err != nil {
+			t.Fatalf("resolveConfig failed: %v", err)
+		}
+		if config.Tool != "claude" || config.MaxIterations != 50 {
+			t.Errorf("Expected overnight values, got %s/%d", config.Tool, config.MaxIterations)
+		}
+		if origins["tool"] != "profile overnight" {
+			t.Errorf("Expected origin 'profile overnight', got '%s'", origins["tool"])
+		}
+	})
+
+	t.Run("profile from env with flag override", func(t *testing.T) {
+		t.Setenv("RALPH_PROFILE", "cautious")
+
+		config, _, err := resolveConfig(ralphDir, "", map[string]any{"max_iterations": 7})
+		if err != nil {
+			t.Fatalf("resolveConfig failed: %v", err)
+		}
+		if config.Tool != "copilot" || config.MaxIterations != 7 {
+			t.Errorf("Expected cautious profile below flags, got %s/%d", config.Tool, config.MaxIterations)
+		}
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		_, _, err := resolveConfig(ralphDir, "weekend", nil)
+		if err == nil || !strings.Contains(err.Error(), "available: cautious, overnight") {
+			t.Errorf("Expected unknown profile error listing profiles, got: %v", err)
+		}
+	})
+
+	t.Run("unknown key in profile", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("profiles:\n  fast:\n    max_iteration: 2\n"), 0644)
+
+		_, _, err := resolveConfig(dir, "", nil)
+		if err == nil || !strings.Contains(err.Error(), "profile fast") {
+			t.Errorf("Expected unknown key error for profile, got: %v", err)
+		}
+	})
+}
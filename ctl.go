@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+// runCtl implements `go-ralph ctl`.
+func runCtl(args []string) {
+	fs := newFlagSet("ctl <pause|resume|skip|stop-after-iteration|status> [story id]", "Control the run in progress in this checkout. Requests take effect between iterations, so the current iteration always finishes.")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	req := ralph.ControlRequest{Command: fs.Arg(0)}
+	switch req.Command {
+	case ralph.ControlPause, ralph.ControlResume, ralph.ControlStopAfterIteration, ralph.ControlStatus:
+	case ralph.ControlSkip:
+		if fs.NArg() < 2 {
+			fmt.Fprintf(os.Stderr, "Error: ctl skip requires a story id\n")
+			os.Exit(2)
+		}
+		req.Story = fs.Arg(1)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown ctl command '%s'\n", req.Command)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	_, ralphDir := ralphDirs()
+	if req.Story != "" {
+		prd, err := ralph.LoadPRD(filepath.Join(ralphDir, "prd.yaml"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading PRD: %v\n", err)
+			os.Exit(1)
+		}
+		if findStory(prd, req.Story) == nil {
+			fmt.Fprintf(os.Stderr, "Error: story '%s' not found in PRD\n", req.Story)
+			os.Exit(1)
+		}
+	}
+
+	state, err := sendControl(ralphDir, req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch req.Command {
+	case ralph.ControlPause:
+		fmt.Println("The run will pause before its next iteration. Resume it with 'go-ralph ctl resume'.")
+	case ralph.ControlResume:
+		fmt.Println("The run will continue.")
+	case ralph.ControlSkip:
+		fmt.Printf("Story %s will be skipped for the rest of the run.\n", req.Story)
+	case ralph.ControlStopAfterIteration:
+		fmt.Println("The run will stop once the current iteration finishes.")
+	}
+	fmt.Printf("Run state: %s\n", describeControl(state))
+}
+
+// sendControl sends a request to the run in progress in ralphDir.
+func sendControl(ralphDir string, req ralph.ControlRequest) (ralph.ControlState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	state, err := ralph.SendControl(ctx, filepath.Join(ralphDir, ralph.SocketFile), req)
+	if err != nil && !fileExists(filepath.Join(ralphDir, ralph.LockFile)) {
+		return state, errors.New("no go-ralph run is in progress in this checkout")
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to reach the run in progress: %w", err)
+	}
+	return state, nil
+}
+
+func describeControl(state ralph.ControlState) string {
+	var parts []string
+	switch {
+	case state.StopAfterIteration:
+		parts = append(parts, "stopping after this iteration")
+	case state.Paused:
+		parts = append(parts, "paused before the next iteration")
+	default:
+		parts = append(parts, "running")
+	}
+	if len(state.Skipped) > 0 {
+		parts = append(parts, "skipping "+strings.Join(state.Skipped, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+func TestSendControl(t *testing.T) {
+	ralphDir := t.TempDir()
+
+	if _, err := sendControl(ralphDir, ralph.ControlRequest{Command: ralph.ControlPause}); err == nil || !strings.Contains(err.Error(), "no go-ralph run is in progress") {
+		t.Errorf("Expected no run in progress, got %v", err)
+	}
+
+	// A run from before control sockets holds the lock but doesn't listen
+	os.WriteFile(filepath.Join(ralphDir, ralph.LockFile), []byte("pid: 1\n"), 0644)
+	if _, err := sendControl(ralphDir, ralph.ControlRequest{Command: ralph.ControlPause}); err == nil || !strings.Contains(err.Error(), "failed to reach the run in progress") {
+		t.Errorf("Expected the run to be unreachable, got %v", err)
+	}
+
+	control := &ralph.Control{}
+	closeControl, err := ralph.ServeControl(filepath.Join(ralphDir, ralph.SocketFile), control)
+	if err != nil {
+		t.Fatalf("ServeControl failed: %v", err)
+	}
+	defer closeControl()
+
+	state, err := sendControl(ralphDir, ralph.ControlRequest{Command: ralph.ControlSkip, Story: "US-002"})
+	if err != nil || describeControl(state) != "running, skipping US-002" {
+		t.Errorf("Unexpected state %q, %v", describeControl(state), err)
+	}
+}
+
+func TestDescribeControl(t *testing.T) {
+	tests := []struct {
+		state ralph.ControlState
+		want  string
+	}{
+		{ralph.ControlState{}, "running"},
+		{ralph.ControlState{Paused: true}, "paused before the next iteration"},
+		{ralph.ControlState{StopAfterIteration: true, Skipped: []string{"US-1", "US-2"}}, "stopping after this iteration, skipping US-1, US-2"},
+	}
+	for _, tt := range tests {
+		if got := describeControl(tt.state); got != tt.want {
+			t.Errorf("describeControl(%+v) = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
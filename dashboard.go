@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+//go:embed dashboard/index.html
+var dashboardHTML []byte
+
+// dashboardOutputLimit caps how much of the current iteration's output is
+// kept for clients that connect mid-iteration.
+const dashboardOutputLimit = 256 * 1024
+
+// dashboard serves a web UI for a ralph directory. Inside `run --ui` it also
+// streams the run's events and the agent output over server-sent events.
+type dashboard struct {
+	ralphDir string
+	live     bool
+
+	mu      sync.Mutex
+	clients map[chan sseMessage]struct{}
+	output  []byte
+	stories []string
+}
+
+type sseMessage struct {
+	event string
+	data  []byte
+}
+
+// DashboardState is the JSON served at /api/state.
+type DashboardState struct {
+	Live     bool               `json:"live"`
+	Lock     string             `json:"lock,omitempty"`
+	PRD      *ralph.PRD         `json:"prd,omitempty"`
+	Next     []string           `json:"next,omitempty"`
+	Working  []string           `json:"working,omitempty"`
+	Runs     []DashboardRun     `json:"runs"`
+	Archives []DashboardArchive `json:"archives"`
+}
+
+// DashboardRun summarizes a run from .ralph/runs.
+type DashboardRun struct {
+	ID         string `json:"id"`
+	Iterations int    `json:"iterations"`
+	Complete   bool   `json:"complete"`
+}
+
+// DashboardArchive summarizes an archived run from .ralph/archive.
+type DashboardArchive struct {
+	Name    string `json:"name"`
+	Project string `json:"project,omitempty"`
+	Passed  int    `json:"passed"`
+	Total   int    `json:"total"`
+}
+
+func newDashboard(ralphDir string, live bool) *dashboard {
+	return &dashboard{ralphDir: ralphDir, live: live, clients: map[chan sseMessage]struct{}{}}
+}
+
+func (d *dashboard) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dashboardHTML)
+	})
+	mux.HandleFunc("GET /api/state", d.serveState)
+	mux.HandleFunc("GET /api/runs/{id}", d.serveRun)
+	mux.HandleFunc("GET /api/events", d.serveEvents)
+	return mux
+}
+
+func (d *dashboard) state() DashboardState {
+	state := DashboardState{Live: d.live, Runs: []DashboardRun{}, Archives: []DashboardArchive{}}
+
+	if lock, err := ralph.ReadLock(filepath.Join(d.ralphDir, ralph.LockFile)); err == nil {
+		state.Lock = lock.String()
+	}
+	if prd, err := ralph.LoadPRD(filepath.Join(d.ralphDir, "prd.yaml")); err == nil {
+		state.PRD = prd
+		for _, story := range ralph.EligibleStories(prd, 1) {
+			state.Next = append(state.Next, story.ID)
+		}
+	}
+	d.mu.Lock()
+	state.Working = d.stories
+	d.mu.Unlock()
+
+	for _, id := range listRuns(d.ralphDir) {
+		records, _ := ralph.LoadJournal(filepath.Join(d.ralphDir, "runs", id, "journal.jsonl"))
+		run := DashboardRun{ID: id, Iterations: len(records)}
+		if len(records) > 0 {
+			run.Complete = records[len(records)-1].Complete
+		}
+		state.Runs = append(state.Runs, run)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(d.ralphDir, "archive"))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		archive := DashboardArchive{Name: entry.Name()}
+		if prd, err := ralph.LoadPRD(filepath.Join(d.ralphDir, "archive", entry.Name(), "prd.yaml")); err == nil {
+			archive.Project = prd.Project
+			archive.Total = len(prd.UserStories)
+			for _, story := range prd.UserStories {
+				if story.Passes {
+					archive.Passed++
+				}
+			}
+		}
+		state.Archives = append(state.Archives, archive)
+	}
+	sort.Slice(state.Archives, func(i, j int) bool { return state.Archives[i].Name > state.Archives[j].Name })
+
+	return state
+}
+
+func (d *dashboard) serveState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, d.state())
+}
+
+func (d *dashboard) serveRun(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		http.NotFound(w, r)
+		return
+	}
+
+	records, err := ralph.LoadJournal(filepath.Join(d.ralphDir, "runs", id, "journal.jsonl"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if records == nil {
+		records = []ralph.IterationRecord{}
+	}
+	writeJSON(w, records)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// serveEvents streams run events, agent output and refresh hints as
+// server-sent events. The current iteration's output so far is sent first as
+// a snapshot.
+func (d *dashboard) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	messages := make(chan sseMessage, 256)
+	d.mu.Lock()
+	d.clients[messages] = struct{}{}
+	output, _ := json.Marshal(string(d.output))
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.clients, messages)
+		d.mu.Unlock()
+	}()
+
+	fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", output)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case message := <-messages:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", message.event, message.data)
+			flusher.Flush()
+		}
+	}
+}
+
+// broadcast sends a message to every connected client, calling update with
+// the lock held first. Clients that can't keep up miss messages rather than
+// holding up the run.
+func (d *dashboard) broadcast(event string, v any, update func()) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if update != nil {
+		update()
+	}
+	for client := range d.clients {
+		select {
+		case client <- sseMessage{event: event, data: data}:
+		default:
+		}
+	}
+}
+
+// Publish forwards a run event to the clients.
+func (d *dashboard) Publish(event ralph.Event) {
+	d.broadcast("event", event, func() {
+		switch event.Type {
+		case ralph.EventIterationStarted:
+			d.output = nil
+			d.stories = event.Stories
+		case ralph.EventRunFinished:
+			d.stories = nil
+		}
+	})
+}
+
+// Write receives the agent output.
+func (d *dashboard) Write(p []byte) (int, error) {
+	d.broadcast("output", string(p), func() {
+		d.output = append(d.output, p...)
+		if len(d.output) > dashboardOutputLimit {
+			d.output = d.output[len(d.output)-dashboardOutputLimit:]
+		}
+	})
+	return len(p), nil
+}
+
+// watch tells clients to refresh when the PRD, the run journals or the lock
+// change on disk, e.g. because a run is going on in another terminal.
+func (d *dashboard) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := d.fingerprint()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if current := d.fingerprint(); current != last {
+				last = current
+				d.broadcast("refresh", struct{}{}, nil)
+			}
+		}
+	}
+}
+
+func (d *dashboard) fingerprint() string {
+	var parts []string
+	paths := []string{filepath.Join(d.ralphDir, "prd.yaml"), filepath.Join(d.ralphDir, ralph.LockFile)}
+	if runIDs := listRuns(d.ralphDir); len(runIDs) > 0 {
+		paths = append(paths, filepath.Join(d.ralphDir, "runs", runIDs[len(runIDs)-1], "journal.jsonl"))
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			parts = append(parts, fmt.Sprintf("%s:%d:%d", path, info.ModTime().UnixNano(), info.Size()))
+		}
+	}
+	return strings.Join(parts, "|")
+}
+
+// start listens on addr and serves the dashboard in the background until ctx
+// is done. It returns the URL of the dashboard.
+func (d *dashboard) start(ctx context.Context, addr string) (string, error) {
+	url, err := serveBackground(ctx, addr, d.handler())
+	if err != nil {
+		return "", err
+	}
+	go d.watch(ctx, 2*time.Second)
+	return url, nil
+}
+
+// serveBackground listens on addr and serves handler until ctx is done. It
+// returns the base URL to reach the listener.
+func serveBackground(ctx context.Context, addr string, handler http.Handler) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port), nil
+}
+
+// runServe implements `go-ralph serve`.
+func runServe(args []string) {
+	fs := newFlagSet("serve [flags]", "Serve a web dashboard with the PRD stories, run history and archived runs. Use `run --ui` to also stream a run's output.")
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on")
+	fs.Parse(args)
+
+	_, ralphDir := ralphDirs()
+	if !fileExists(ralphDir) {
+		fmt.Fprintf(os.Stderr, "Error: .ralph not found\n")
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	url, err := newDashboard(ralphDir, false).start(ctx, *addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Dashboard: %s (Ctrl+C to stop)\n", url)
+
+	<-ctx.Done()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+func setupDashboardDir(t *testing.T) string {
+	t.Helper()
+	ralphDir := t.TempDir()
+	os.WriteFile(filepath.Join(ralphDir, "prd.yaml"), []byte(`project: Test
+branchName: ralph/feature
+userStories:
+- id: US-001
+  title: Done
+  priority: 1
+  passes: true
+- id: US-002
+  title: Next
+  priority: 2
+`), 0644)
+
+	runDir := filepath.Join(ralphDir, "runs", "20260124-103000")
+	os.MkdirAll(runDir, 0755)
+	os.WriteFile(filepath.Join(runDir, "journal.jsonl"), []byte(`{"iteration":1,"exit_code":0,"commits":[{"hash":"abc1234","subject":"feat: US-001"}],"complete":true}`+"\n"), 0644)
+
+	archiveDir := filepath.Join(ralphDir, "archive", "2026-01-01-ralph-old")
+	os.MkdirAll(archiveDir, 0755)
+	os.WriteFile(filepath.Join(archiveDir, "prd.yaml"), []byte("project: Old\nuserStories:\n- id: US-1\n  passes: true\n"), 0644)
+
+	return ralphDir
+}
+
+func getJSON(t *testing.T, url string, v any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Decoding %s failed: %v", url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestDashboardAPI(t *testing.T) {
+	d := newDashboard(setupDashboardDir(t), false)
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	var state DashboardState
+	getJSON(t, server.URL+"/api/state", &state)
+	if state.PRD == nil || len(state.PRD.UserStories) != 2 || !state.PRD.UserStories[0].Passes {
+		t.Errorf("Expected PRD stories, got %+v", state.PRD)
+	}
+	if len(state.Next) != 1 || state.Next[0] != "US-002" {
+		t.Errorf("Expected US-002 next, got %v", state.Next)
+	}
+	if len(state.Runs) != 1 || state.Runs[0].ID != "20260124-103000" || !state.Runs[0].Complete {
+		t.Errorf("Unexpected runs: %+v", state.Runs)
+	}
+	if len(state.Archives) != 1 || state.Archives[0].Project != "Old" || state.Archives[0].Passed != 1 {
+		t.Errorf("Unexpected archives: %+v", state.Archives)
+	}
+
+	var records []ralph.IterationRecord
+	if status := getJSON(t, server.URL+"/api/runs/20260124-103000", &records); status != http.StatusOK {
+		t.Fatalf("Expected run records, got status %d", status)
+	}
+	if len(records) != 1 || len(records[0].Commits) != 1 || records[0].Commits[0].Subject != "feat: US-001" {
+		t.Errorf("Unexpected records: %+v", records)
+	}
+	for _, id := range []string{"missing", "..", "..%2Fprd.yaml"} {
+		if status := getJSON(t, server.URL+"/api/runs/"+id, &records); status != http.StatusNotFound {
+			t.Errorf("Expected 404 for run %q, got %d", id, status)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("Expected the dashboard page, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestDashboardEvents(t *testing.T) {
+	d := newDashboard(setupDashboardDir(t), true)
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	d.Write([]byte("before connecting\n"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/events failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() (string, string) {
+		t.Helper()
+		var event, data string
+		for lines.Scan() {
+			line := lines.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			case line == "":
+				return event, data
+			}
+		}
+		t.Fatalf("Event stream ended: %v", lines.Err())
+		return "", ""
+	}
+
+	if event, data := next(); event != "snapshot" || data != `"before connecting\n"` {
+		t.Errorf("Expected output snapshot, got %s %s", event, data)
+	}
+
+	d.Publish(ralph.Event{Type: ralph.EventIterationStarted, Iteration: 2, Stories: []string{"US-002"}})
+	d.Write([]byte("working on it\n"))
+
+	if event, data := next(); event != "event" || !strings.Contains(data, `"type":"iteration_started"`) {
+		t.Errorf("Expected iteration_started event, got %s %s", event, data)
+	}
+	if event, data := next(); event != "output" || data != `"working on it\n"` {
+		t.Errorf("Expected output, got %s %s", event, data)
+	}
+
+	// A new iteration starts with a fresh output buffer
+	if state := d.state(); len(state.Working) != 1 || state.Working[0] != "US-002" {
+		t.Errorf("Expected US-002 in progress, got %v", state.Working)
+	}
+	if string(d.output) != "working on it\n" {
+		t.Errorf("Expected output of the current iteration only, got %q", d.output)
+	}
+}
+
+func TestDashboardWatch(t *testing.T) {
+	ralphDir := setupDashboardDir(t)
+	d := newDashboard(ralphDir, false)
+	messages := make(chan sseMessage, 1)
+	d.clients[messages] = struct{}{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.watch(ctx, 10*time.Millisecond)
+
+	time.Sleep(30 * time.Millisecond)
+	os.WriteFile(filepath.Join(ralphDir, "prd.yaml"), []byte("project: Changed\n"), 0644)
+
+	select {
+	case message := <-messages:
+		if message.event != "refresh" {
+			t.Errorf("Expected refresh, got %s", message.event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected a refresh after the PRD changed")
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+// printDryRun describes what `go-ralph run` would do with config, from the
+// file migrations and archiving to the exact prompt and command line sent to
+// the agent, without changing anything.
+func printDryRun(w io.Writer, workDir, ralphDir string, config *ralph.Config, parallel int, allowDirty bool) error {
+	prd, err := ralph.LoadPRD(filepath.Join(ralphDir, "prd.yaml"))
+	if err != nil {
+		return fmt.Errorf("loading PRD: %w", err)
+	}
+	prompt, err := os.ReadFile(filepath.Join(ralphDir, config.ToolPromptFile()))
+	if err != nil {
+		return fmt.Errorf("reading prompt: %w", err)
+	}
+
+	fmt.Fprintf(w, "Dry run - Tool: %s - Max iterations: %d\n", config.Tool, config.MaxIterations)
+
+	fmt.Fprintln(w, "\nPlanned actions:")
+	for _, file := range versionedFiles(ralphDir) {
+		if file.path == "" || !fileExists(file.path) {
+			continue
+		}
+		version, err := fileVersion(file.path)
+		if err != nil {
+			return err
+		}
+		if latest := latestVersion(file.migrations); version < latest {
+			path := file.path
+			if rel, err := filepath.Rel(workDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+			fmt.Fprintf(w, "  - Migrate %s from version %d to %d\n", path, version, latest)
+		}
+	}
+	if lastBranch := ralph.PendingArchive(ralphDir); lastBranch != "" {
+		fmt.Fprintf(w, "  - Archive the previous run (%s) and start a fresh progress.txt\n", lastBranch)
+	}
+	if prd.BranchName != "" {
+		if !allowDirty {
+			files, err := ralph.DirtyFiles(workDir)
+			if err != nil {
+				return fmt.Errorf("checking working tree: %w", err)
+			}
+			if len(files) > 0 {
+				fmt.Fprintf(w, "  - Stop: working tree has %d uncommitted change(s), commit them or use --allow-dirty\n", len(files))
+			}
+		}
+		current, _ := ralph.CurrentBranch(workDir)
+		switch {
+		case current == prd.BranchName:
+			fmt.Fprintf(w, "  - Stay on branch %s\n", prd.BranchName)
+		case ralph.BranchExists(workDir, prd.BranchName):
+			fmt.Fprintf(w, "  - Check out branch %s\n", prd.BranchName)
+		default:
+			fmt.Fprintf(w, "  - Create branch %s from %s\n", prd.BranchName, config.BaseBranchOrDefault())
+		}
+	}
+
+	fmt.Fprintln(w, "\nChecks after each iteration:")
+	fmt.Fprintln(w, "  - Agent exit code")
+	fmt.Fprintln(w, "  - Uncommitted changes left behind")
+	fmt.Fprintln(w, "  - <promise>COMPLETE</promise> in the agent output")
+	if config.RollbackOnFailure {
+		fmt.Fprintln(w, "  - Failed iterations are rolled back (rollback_on_failure)")
+	}
+	var approvals []string
+	for _, story := range prd.UserStories {
+		if !story.Passes && config.RequiresApproval(story) {
+			approvals = append(approvals, story.ID)
+		}
+	}
+	if len(approvals) > 0 {
+		fmt.Fprintf(w, "  - Wait for your approval of %s once passing, rejections are retried with your feedback\n", strings.Join(approvals, ", "))
+	}
+
+	var hooks []string
+	for _, name := range []string{ralph.HookPreRun, ralph.HookPreIteration, ralph.HookPostIteration, ralph.HookOnComplete, ralph.HookOnFailure} {
+		for _, command := range config.Hooks.Commands(name) {
+			hooks = append(hooks, fmt.Sprintf("  - %s: %s\n", name, command))
+		}
+	}
+	if len(hooks) > 0 {
+		fmt.Fprintln(w, "\nHooks:")
+		for _, hook := range hooks {
+			fmt.Fprint(w, hook)
+		}
+		if len(config.Hooks.PreIteration) > 0 && config.Hooks.PreIterationFailure == ralph.HookFailureSkip {
+			fmt.Fprintln(w, "  A failing pre_iteration hook skips the iteration")
+		}
+	}
+
+	var notify []string
+	for _, n := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"bell", config.Notifications.Bell},
+		{"osc9", config.Notifications.OSC9},
+		{"desktop", config.Notifications.Desktop},
+	} {
+		if n.enabled {
+			notify = append(notify, n.name)
+		}
+	}
+	if len(notify) > 0 {
+		fmt.Fprintf(w, "\nNotify when the run ends: %s\n", strings.Join(notify, ", "))
+	}
+	for _, hook := range config.Notifications.Webhooks {
+		events := hook.Events
+		if len(events) == 0 {
+			events = ralph.DefaultWebhookEvents
+		}
+		names := make([]string, len(events))
+		for i, event := range events {
+			names[i] = string(event)
+		}
+		fmt.Fprintf(w, "Post %s to webhook %s\n", strings.Join(names, ", "), hook.URL)
+	}
+
+	args := config.ToolArgs[config.Tool]
+	stories := ralph.EligibleStories(prd, parallel)
+	if len(stories) == 0 {
+		fmt.Fprintln(w, "\nNo story is ready: every story passes or waits on a dependency.")
+		return nil
+	}
+
+	if parallel > 1 {
+		for _, story := range stories {
+			fmt.Fprintf(w, "\nStory %s - %s\n", story.ID, story.Title)
+			fmt.Fprintf(w, "Command (in .ralph/worktrees/%s): %s\n", story.ID, shellJoin(config.Tool, args))
+			fmt.Fprintf(w, "\n----- prompt (stdin) -----\n%s\n----- end of prompt -----\n", ralph.StoryPrompt(prompt, story))
+		}
+		return nil
+	}
+
+	story := stories[0]
+	fmt.Fprintf(w, "\nNext story: %s - %s (the agent picks the highest priority story that doesn't pass)\n", story.ID, story.Title)
+	fmt.Fprintf(w, "Command: %s < %s\n", shellJoin(config.Tool, args), filepath.Join(".ralph", config.ToolPromptFile()))
+	fmt.Fprintf(w, "\n----- prompt (stdin) -----\n%s\n----- end of prompt -----\n", prompt)
+
+	return nil
+}
+
+// shellJoin formats a command line for copy and paste into a POSIX shell.
+func shellJoin(name string, args []string) string {
+	parts := []string{shellQuote(name)}
+	for _, arg := range args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,@+%", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
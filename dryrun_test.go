@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+
+	t.Setenv("GIT_AUTHOR_NAME", "Ralph Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ralph@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Ralph Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "ralph@example.com")
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--initial-branch=main"},
+		{"commit", "--allow-empty", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %v\n%s", args[0], err, out)
+		}
+	}
+
+	return dir
+}
+
+func TestPrintDryRun(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := initTestRepo(t)
+	ralphDir := filepath.Join(dir, ".ralph")
+	os.MkdirAll(ralphDir, 0755)
+	os.WriteFile(filepath.Join(ralphDir, "prompt.md"), []byte("Do the next story.\n"), 0644)
+	os.WriteFile(filepath.Join(ralphDir, ".last-branch"), []byte("ralph/old"), 0644)
+	os.WriteFile(filepath.Join(ralphDir, "prd.yaml"), []byte(`project: Test
+branchName: ralph/feature
+userStories:
+- id: US-001
+  title: Later story
+  priority: 2
+- id: US-002
+  title: First story
+  priority: 1
+  requireApproval: true
+`), 0644)
+
+	config := &ralph.Config{
+		Tool:          "claude",
+		MaxIterations: 5,
+		PromptFile:    "prompt.md",
+		ToolArgs:      map[string][]string{"claude": {"--print", "shell(git push)"}},
+		Hooks:         ralph.Hooks{PreRun: []string{"docker compose up -d"}},
+		Notifications: ralph.Notifications{Bell: true, Desktop: true, Webhooks: []ralph.Webhook{{URL: "https://hooks.example.com/ralph"}}},
+	}
+
+	t.Run("sequential", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := printDryRun(&out, dir, ralphDir, config, 1, false); err != nil {
+			t.Fatalf("printDryRun failed: %v", err)
+		}
+
+		for _, expected := range []string{
+			"Migrate .ralph/prd.yaml from version 0 to 1",
+			"Archive the previous run (ralph/old)",
+			"Create branch ralph/feature from main",
+			"pre_run: docker compose up -d",
+			"Notify when the run ends: bell, desktop",
+			"Wait for your approval of US-002 once passing",
+			"Post run_started, story_passed, run_finished to webhook https://hooks.example.com/ralph",
+			"Next story: US-002 - First story",
+			"Command: claude --print 'shell(git push)' < .ralph/prompt.md",
+			"Do the next story.",
+		} {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("Expected %q in output:\n%s", expected, out.String())
+			}
+		}
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := printDryRun(&out, dir, ralphDir, config, 2, false); err != nil {
+			t.Fatalf("printDryRun failed: %v", err)
+		}
+		if strings.Count(out.String(), "## Assigned Story") != 2 {
+			t.Errorf("Expected a prompt per story, got:\n%s", out.String())
+		}
+	})
+
+	// Nothing was changed
+	if branch, _ := ralph.CurrentBranch(dir); branch != "main" {
+		t.Errorf("Expected to stay on main, got %s", branch)
+	}
+	if fileExists(filepath.Join(ralphDir, "progress.txt")) || fileExists(filepath.Join(ralphDir, "runs")) || fileExists(filepath.Join(ralphDir, "archive")) {
+		t.Error("Expected dry run not to create files")
+	}
+	if version, _ := fileVersion(filepath.Join(ralphDir, "prd.yaml")); version != 0 {
+		t.Error("Expected dry run not to migrate the PRD")
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	got := shellJoin("copilot", []string{"--allow-all-tools", "shell(git push)", "it's", ""})
+	expected := `copilot --allow-all-tools 'shell(git push)' 'it'\''s' ''`
+	if got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
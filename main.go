@@ -3,17 +3,21 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	_ "embed"
-	"flag"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/jlucaspains/go-ralph/ralph"
+	"golang.org/x/term"
 	"gopkg.in/yaml.v3"
 )
 
@@ -32,57 +36,40 @@ var prdGeneratorSkill string
 //go:embed templates/skills/prd-converter.md
 var prdConverterSkill string
 
-type Config struct {
-	Tool          string              `yaml:"tool"`
-	MaxIterations int                 `yaml:"max_iterations"`
-	AutoArchive   bool                `yaml:"auto_archive"`
-	PromptFile    string              `yaml:"prompt_file"`
-	ToolArgs      map[string][]string `yaml:"tool_args"`
-}
-
-type PRD struct {
-	Project     string      `yaml:"project"`
-	BranchName  string      `yaml:"branchName"`
-	Description string      `yaml:"description"`
-	UserStories []UserStory `yaml:"userStories"`
-}
-
-type UserStory struct {
-	ID                 string   `yaml:"id"`
-	Title              string   `yaml:"title"`
-	Description        string   `yaml:"description"`
-	AcceptanceCriteria []string `yaml:"acceptanceCriteria"`
-	Priority           int      `yaml:"priority"`
-	Passes             bool     `yaml:"passes"`
-	Notes              string   `yaml:"notes"`
-}
-
-func main() {
-	initMode := flag.Bool("init", false, "Initialize ralph directory with config and templates")
-	tool := flag.String("tool", "", "Tool to use (required for --init): claude or copilot")
-	maxIterations := flag.Int("max-iterations", 0, "Maximum number of iterations (overrides config)")
-	flag.Parse()
-
-	// Handle positional argument for max iterations (backwards compatibility)
-	if flag.NArg() > 0 {
-		if n, err := strconv.Atoi(flag.Arg(0)); err == nil {
-			*maxIterations = n
-		}
+//go:embed templates/gitignore
+var gitignoreTemplate string
+
+// runRun implements `go-ralph run`, the agent loop.
+func runRun(args []string) {
+	fs := newFlagSet("run [flags] [max-iterations]", "Run the agent loop until all stories pass or max iterations is reached.")
+	maxIterations := fs.Int("max-iterations", 0, "Maximum number of iterations (overrides config)")
+	allowDirty := fs.Bool("allow-dirty", false, "Allow starting with uncommitted changes in the working tree")
+	parallel := fs.Int("parallel", 1, "Number of stories to work on at once, each in its own git worktree")
+	profile := fs.String("profile", "", "Config profile to use (overrides RALPH_PROFILE)")
+	dryRun := fs.Bool("dry-run", false, "Print the prompt, command line and planned actions without running the agent")
+	ui := fs.String("ui", "", "Serve a live web dashboard of the run on this address, e.g. 127.0.0.1:8080")
+	metricsAddr := fs.String("metrics-addr", "", "Serve Prometheus metrics of the run at /metrics on this address, e.g. 127.0.0.1:9090")
+	traceFile := fs.String("trace-file", "", "Append a trace of the run as OTLP JSON to this file")
+	traceEndpoint := fs.String("trace-endpoint", "", "Send a trace of the run to this OTLP/HTTP collector, e.g. http://localhost:4318")
+	tuiMode := fs.Bool("tui", false, "Show the run in an interactive terminal UI with keys to pause, skip the current story or abort")
+	fs.Parse(args)
+
+	// Handle positional argument for max iterations (backwards compatibility).
+	// Flags stop at the first positional argument, so anything after it would
+	// be dropped silently.
+	if fs.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "Error: unexpected argument '%s', flags go before max-iterations\n", fs.Arg(1))
+		fs.Usage()
+		os.Exit(2)
 	}
-
-	// Init mode
-	if *initMode {
-		if *tool == "" {
-			fmt.Fprintf(os.Stderr, "Error: --tool flag is required for --init\n")
-			fmt.Fprintf(os.Stderr, "Usage: go-ralph --init --tool=<claude|copilot>\n")
-			os.Exit(1)
+	if fs.NArg() > 0 {
+		n, err := strconv.Atoi(fs.Arg(0))
+		if err != nil || n < 1 {
+			fmt.Fprintf(os.Stderr, "Error: max-iterations must be a positive number, got '%s'\n", fs.Arg(0))
+			fs.Usage()
+			os.Exit(2)
 		}
-		if *tool != "claude" && *tool != "copilot" {
-			fmt.Fprintf(os.Stderr, "Error: Invalid tool '%s'. Must be 'claude' or 'copilot'.\n", *tool)
-			os.Exit(1)
-		}
-		runInit(*tool)
-		return
+		*maxIterations = n
 	}
 
 	// Run mode - load config
@@ -98,198 +85,458 @@ func main() {
 	// Load config
 	if !fileExists(configFile) {
 		fmt.Fprintf(os.Stderr, "Error: .ralph/config.yaml not found\n")
-		fmt.Fprintf(os.Stderr, "Run 'go-ralph --init --tool=<claude|copilot>' first to initialize\n")
+		fmt.Fprintf(os.Stderr, "Run 'go-ralph init --tool=<claude|copilot>' first to initialize\n")
 		os.Exit(1)
 	}
 
-	config, err := loadConfig(configFile)
+	// Take the run lock first so another run never sees a half migrated file,
+	// and release it on every exit since os.Exit skips deferred calls
+	var lock *ralph.RunLock
+	if !*dryRun {
+		var stale *ralph.RunLock
+		lock, stale, err = ralph.AcquireLock(filepath.Join(ralphDir, ralph.LockFile))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if stale != nil {
+			fmt.Fprintf(os.Stderr, "Warning: removed stale lock %s\n", filepath.Join(ralphDir, ralph.LockFile))
+		}
+	}
+	defer lock.Release()
+	exit := func(code int) {
+		lock.Release()
+		os.Exit(code)
+	}
+
+	// Upgrade config and PRD files written by older versions
+	if !*dryRun {
+		migrations, err := migrateRalphFiles(ralphDir)
+		printMigrationResults(migrations, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+	}
+
+	// Layer global, repo and local config files, RALPH_* env vars and flags
+	flagValues := map[string]any{}
+	if *maxIterations > 0 {
+		flagValues["max_iterations"] = *maxIterations
+	}
+	config, _, err := resolveConfig(ralphDir, *profile, flagValues)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
-	// Override max iterations if provided
-	if *maxIterations > 0 {
-		config.MaxIterations = *maxIterations
+	warnings, err := validateConfig(config, ralphDir)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config:\n%v\n", err)
+		exit(1)
 	}
 
-	prdFile := filepath.Join(ralphDir, "prd.yaml")
-	progressFile := filepath.Join(ralphDir, "progress.txt")
-	archiveDir := filepath.Join(ralphDir, "archive")
-	lastBranchFile := filepath.Join(ralphDir, ".last-branch")
+	if *dryRun {
+		if err := printDryRun(os.Stdout, workDir, ralphDir, config, *parallel, *allowDirty); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		return
+	}
 
-	// Archive previous run if branch changed
-	if fileExists(prdFile) && fileExists(lastBranchFile) {
-		currentBranch := getBranchFromPRD(prdFile)
-		lastBranch := readFile(lastBranchFile)
+	if *tuiMode && (!term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd()))) {
+		fmt.Fprintf(os.Stderr, "Error: --tui needs an interactive terminal\n")
+		exit(1)
+	}
 
-		if currentBranch != "" && lastBranch != "" && currentBranch != lastBranch {
-			// Archive the previous run
-			date := time.Now().Format("2006-01-02")
-			folderName := strings.TrimPrefix(lastBranch, ".ralph/")
-			archiveFolder := filepath.Join(archiveDir, date+"-"+folderName)
+	// Stop the agent and release the lock on Ctrl-C, or on abort in the TUI
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, abort := context.WithCancel(ctx)
+	defer abort()
+
+	runner := &ralph.Runner{
+		Config:        config,
+		WorkDir:       workDir,
+		RalphDir:      ralphDir,
+		Parallel:      *parallel,
+		AllowDirty:    *allowDirty,
+		Stdout:        os.Stdout,
+		Stderr:        os.Stderr,
+		OnEvent:       printEvent(config),
+		Control:       &ralph.Control{},
+		ControlSocket: filepath.Join(ralphDir, ralph.SocketFile),
+		Approver:      newTerminalApprover(os.Stdin, os.Stdout),
+		Delay:         2 * time.Second,
+		Lock:          lock,
+	}
 
-			fmt.Printf("Archiving previous run: %s\n", lastBranch)
-			if err := os.MkdirAll(archiveFolder, 0755); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to create archive folder: %v\n", err)
-			} else {
-				copyFile(prdFile, filepath.Join(archiveFolder, "prd.yaml"))
-				copyFile(progressFile, filepath.Join(archiveFolder, "progress.txt"))
-				fmt.Printf("   Archived to: %s\n", archiveFolder)
-			}
+	// The TUI replaces the printed banners and shows the agent output in a pane
+	var screen *tui
+	if *tuiMode {
+		screen = newTUI(config, ralphDir, runner.Control, abort)
+		runner.OnEvent = screen.OnEvent
+		runner.Approver = screen
+		runner.Stdout = screen
+		runner.Stderr = screen
+		runner.Notifiers = config.Notifications.Notifiers(os.Stdout)
+	}
 
-			// Reset progress file for new run
-			initProgressFile(progressFile)
+	// Stream events and agent output to the dashboard
+	if *ui != "" {
+		dashboard := newDashboard(ralphDir, true)
+		url, err := dashboard.start(ctx, *ui)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting dashboard: %v\n", err)
+			exit(1)
 		}
-	}
+		fmt.Printf("Dashboard: %s\n", url)
 
-	// Track current branch
-	if fileExists(prdFile) {
-		currentBranch := getBranchFromPRD(prdFile)
-		if currentBranch != "" {
-			writeFile(lastBranchFile, currentBranch)
+		printer := runner.OnEvent
+		runner.OnEvent = func(event ralph.Event) {
+			printer(event)
+			dashboard.Publish(event)
 		}
+		runner.Stdout = io.MultiWriter(runner.Stdout, dashboard)
+		runner.Stderr = io.MultiWriter(runner.Stderr, dashboard)
+		runner.Notifiers = config.Notifications.Notifiers(os.Stdout)
 	}
 
-	// Initialize progress file if it doesn't exist
-	if !fileExists(progressFile) {
-		initProgressFile(progressFile)
+	var project string
+	if prd, err := ralph.LoadPRD(filepath.Join(ralphDir, "prd.yaml")); err == nil {
+		project = prd.Project
 	}
 
-	fmt.Printf("Starting Ralph - Tool: %s - Max iterations: %d\n", config.Tool, config.MaxIterations)
-
-	// Run iterations
-	for i := 1; i <= config.MaxIterations; i++ {
-		fmt.Println()
-		fmt.Println("===============================================================")
-		fmt.Printf("  Ralph Iteration %d of %d (%s)\n", i, config.MaxIterations, config.Tool)
-		fmt.Println("===============================================================")
-
-		// Get tool args from config
-		args := config.ToolArgs[config.Tool]
-		if args == nil {
-			args = []string{}
+	// Count iterations, durations and exit codes for Prometheus
+	if *metricsAddr != "" {
+		metrics := newMetrics(project, config.Tool)
+		url, err := serveBackground(ctx, *metricsAddr, metrics.handler())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting metrics listener: %v\n", err)
+			exit(1)
 		}
+		fmt.Printf("Metrics: %s/metrics\n", url)
 
-		// Run the selected tool with the ralph prompt
-		output, err := runToolWithInput(ralphDir, config.Tool, args, config.PromptFile)
+		observed := runner.OnEvent
+		runner.OnEvent = func(event ralph.Event) {
+			observed(event)
+			metrics.Observe(event)
+		}
+	}
 
-		// Continue even on error (|| true behavior)
-		if err != nil {
-			// Error already shown via tee to stderr
+	// Collect spans for the trace exported once the run ends
+	var spans []ralph.Span
+	if *traceFile != "" || *traceEndpoint != "" {
+		runner.OnSpan = func(span ralph.Span) {
+			spans = append(spans, span)
 		}
+	}
 
-		// Check for completion signal
-		if strings.Contains(output, "<promise>COMPLETE</promise>") {
-			fmt.Println()
-			fmt.Println("Ralph completed all tasks!")
-			fmt.Printf("Completed at iteration %d of %d\n", i, config.MaxIterations)
-			os.Exit(0)
+	restore := func() {}
+	if screen != nil {
+		if restore, err = screen.start(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting TUI: %v\n", err)
+			exit(1)
 		}
+	}
+	result, err := runner.Run(ctx)
+	restore()
+	if len(spans) > 0 {
+		if err := exportTrace(spans, project, *traceFile, *traceEndpoint); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to export trace: %v\n", err)
+		}
+	}
 
-		fmt.Printf("Iteration %d complete. Continuing...\n", i)
-		time.Sleep(2 * time.Second)
+	var dirty *ralph.DirtyTreeError
+	switch {
+	case ctx.Err() != nil:
+		fmt.Println()
+		fmt.Println("Ralph was interrupted.")
+		printRunSummary(result.Records)
+		exit(130)
+	case errors.As(err, &dirty):
+		fmt.Fprintf(os.Stderr, "Error: working tree has uncommitted changes:\n")
+		for _, file := range dirty.Files {
+			fmt.Fprintf(os.Stderr, "  %s\n", file)
+		}
+		fmt.Fprintf(os.Stderr, "Commit or stash them first, or run with --allow-dirty\n")
+		exit(1)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		printRunSummary(result.Records)
+		exit(1)
+	case result.Complete:
+		fmt.Println()
+		fmt.Println("Ralph completed all tasks!")
+		fmt.Printf("Completed at iteration %d of %d\n", len(result.Records), config.MaxIterations)
+		printRunSummary(result.Records)
+		return
+	case result.Reason == ralph.StopRequested:
+		fmt.Println()
+		fmt.Println("Ralph stopped before completing all tasks.")
+		fmt.Printf("Check %s for status.\n", filepath.Join(ralphDir, "progress.txt"))
+		printRunSummary(result.Records)
+		exit(1)
 	}
 
 	fmt.Println()
-	fmt.Printf("Ralph reached max iterations (%d) without completing all tasks.\n", config.MaxIterations)
-	fmt.Printf("Check %s for status.\n", progressFile)
-	os.Exit(1)
+	if *parallel > 1 {
+		fmt.Printf("Ralph stopped without completing all tasks.\n")
+	} else {
+		fmt.Printf("Ralph reached max iterations (%d) without completing all tasks.\n", config.MaxIterations)
+	}
+	fmt.Printf("Check %s for status.\n", filepath.Join(ralphDir, "progress.txt"))
+	printRunSummary(result.Records)
+	exit(1)
 }
 
-func runInit(tool string) {
-	workDir, err := os.Getwd()
+// printEvent prints the progress of a run as it happens.
+func printEvent(config *ralph.Config) func(ralph.Event) {
+	return func(event ralph.Event) {
+		switch event.Type {
+		case ralph.EventIterationStarted:
+			fmt.Println()
+			fmt.Println("===============================================================")
+			if len(event.Stories) > 0 {
+				fmt.Printf("  Ralph Iteration %d of %d (%s) - %s\n", event.Iteration, config.MaxIterations, config.Tool, strings.Join(event.Stories, ", "))
+			} else {
+				fmt.Printf("  Ralph Iteration %d of %d (%s)\n", event.Iteration, config.MaxIterations, config.Tool)
+			}
+			fmt.Println("===============================================================")
+		case ralph.EventIterationFinished:
+			if !event.Record.Complete {
+				fmt.Printf("Iteration %d complete. Continuing...\n", event.Iteration)
+			}
+		case ralph.EventWarning:
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", event.Message)
+		case ralph.EventRunFinished:
+		default:
+			fmt.Println(event.Message)
+		}
+	}
+}
+
+// runInitCommand implements `go-ralph init`.
+func runInitCommand(args []string) {
+	fs := newFlagSet("init --tool=<claude|copilot|claude,copilot|all> [flags]", "Initialize .ralph with the config, agent prompt and PRD skills for one or more tools.")
+	tool := fs.String("tool", "", "Tool to use: claude, copilot, a comma-separated list or all (required)")
+	force := fs.Bool("force", false, "Overwrite existing files without asking")
+	skipExisting := fs.Bool("skip-existing", false, "Keep existing files without asking")
+	dryRun := fs.Bool("dry-run", false, "Print the planned file operations without writing anything")
+	upgrade := fs.Bool("upgrade", false, "Merge the latest prompt and skill templates into the existing files, keeping local edits")
+	templates := fs.String("templates", "", "Directory with custom templates, same layout as templates/ (overrides templates_dir)")
+	fs.Parse(args)
+
+	if *upgrade && *tool == "" {
+		// Upgrades default to the tools the repo was initialized for
+		workDir, ralphDir := ralphDirs()
+		if manifest, err := loadTemplateManifest(workDir); err == nil && len(manifest.Tools) > 0 {
+			*tool = strings.Join(manifest.Tools, ",")
+		} else if config, err := loadConfig(filepath.Join(ralphDir, "config.yaml")); err == nil {
+			*tool = config.Tool
+		}
+	}
+	if *tool == "" {
+		fmt.Fprintf(os.Stderr, "Error: --tool flag is required for init\n")
+		fmt.Fprintf(os.Stderr, "Usage: go-ralph init --tool=<claude|copilot|claude,copilot|all>\n")
+		os.Exit(1)
+	}
+	tools, err := parseTools(*tool)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting current directory: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *force && *skipExisting {
+		fmt.Fprintf(os.Stderr, "Error: --force and --skip-existing can't be used together\n")
+		os.Exit(1)
+	}
+	if *upgrade && (*force || *skipExisting) {
+		fmt.Fprintf(os.Stderr, "Error: --upgrade can't be used with --force or --skip-existing\n")
 		os.Exit(1)
 	}
 
-	ralphDir := filepath.Join(workDir, ".ralph")
-	fmt.Printf("Initializing Ralph for tool: %s\n\n", tool)
-
-	// Create .ralph directory
-	if err := os.MkdirAll(ralphDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating .ralph directory: %v\n", err)
+	_, ralphDir := ralphDirs()
+	templatesDir, err := resolveTemplatesDir(*templates, ralphDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Write config.yaml
-	configPath := filepath.Join(ralphDir, "config.yaml")
-	if !promptOverwrite(configPath) {
-		fmt.Println("Skipped config.yaml")
-	} else {
-		configContent := strings.Replace(configTemplate, "{{.Tool}}", tool, 1)
-		if err := writeFile(configPath, configContent); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing config.yaml: %v\n", err)
-			os.Exit(1)
+	opts := InitOptions{Force: *force, SkipExisting: *skipExisting, DryRun: *dryRun, TemplatesDir: templatesDir}
+	if *upgrade {
+		runUpgrade(tools, opts)
+		return
+	}
+
+	runInit(tools, opts)
+}
+
+// parseTools parses the --tool value: a single tool, a comma-separated list
+// or "all".
+func parseTools(value string) ([]string, error) {
+	if value == "all" {
+		return knownTools, nil
+	}
+
+	var tools []string
+	for _, tool := range strings.Split(value, ",") {
+		tool = strings.TrimSpace(tool)
+		if !isKnownTool(tool) {
+			return nil, fmt.Errorf("Invalid tool '%s'. Must be 'claude', 'copilot' or 'all'.", tool)
+		}
+		if !contains(tools, tool) {
+			tools = append(tools, tool)
 		}
-		fmt.Println("✓ Created .ralph/config.yaml")
 	}
 
-	// Write prompt.md
-	promptPath := filepath.Join(ralphDir, "prompt.md")
-	if !promptOverwrite(promptPath) {
-		fmt.Println("Skipped prompt.md")
-	} else {
-		var promptContent string
-		switch tool {
-		case "claude":
-			promptContent = claudePrompt
-		case "copilot":
-			promptContent = copilotPrompt
+	return tools, nil
+}
+
+// InitOptions controls where init reads templates from and how it treats
+// files that already exist.
+type InitOptions struct {
+	Force        bool
+	SkipExisting bool
+	DryRun       bool
+	TemplatesDir string
+}
+
+type initFile struct {
+	path    string
+	content string
+	// template names the template the file comes from, for
+	// `init --upgrade`. Empty for files upgraded by migrations instead.
+	template string
+}
+
+// initPromptFile returns the prompt_file of the existing config in ralphDir,
+// so init and upgrades write the prompt the config points at. New configs use
+// prompt.md, or prompt.{tool}.md when several tools are initialized so that
+// switching `tool:` in the config needs no new init.
+func initPromptFile(ralphDir string, tools []string) string {
+	if config, err := loadConfig(filepath.Join(ralphDir, "config.yaml")); err == nil && config.PromptFile != "" {
+		return config.PromptFile
+	}
+	if len(tools) > 1 {
+		return "prompt.{tool}.md"
+	}
+	return "prompt.md"
+}
+
+// initFiles returns the files init writes for tools, relative to workDir.
+// Templates are read from templatesDir when it has them, else the embedded
+// ones are used. The config selects the first tool. Each tool's prompt goes
+// to promptFile with {tool} replaced. Without the placeholder only the first
+// tool gets a prompt.
+func initFiles(tools []string, promptFile, templatesDir string) ([]initFile, error) {
+	config, err := readTemplate(templatesDir, "config.yaml")
+	if err != nil {
+		return nil, err
+	}
+	config = strings.NewReplacer("{{.Tool}}", tools[0], "{{.PromptFile}}", promptFile).Replace(config)
+	files := []initFile{{filepath.Join(".ralph", "config.yaml"), config, ""}}
+
+	for _, tool := range tools {
+		skillsBaseDir := filepath.Join(".claude", "skills")
+		if tool == "copilot" {
+			skillsBaseDir = filepath.Join(".github", "skills")
 		}
-		if err := writeFile(promptPath, promptContent); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing prompt.md: %v\n", err)
-			os.Exit(1)
+
+		for _, file := range []initFile{
+			{filepath.Join(".ralph", strings.ReplaceAll(promptFile, "{tool}", tool)), "", tool + "/prompt.md"},
+			{filepath.Join(skillsBaseDir, "prd-generator", "SKILL.md"), "", "skills/prd-generator.md"},
+			{filepath.Join(skillsBaseDir, "prd-converter", "SKILL.md"), "", "skills/prd-converter.md"},
+		} {
+			if hasInitFile(files, file.path) {
+				continue
+			}
+			if file.content, err = readTemplate(templatesDir, file.template); err != nil {
+				return nil, err
+			}
+			files = append(files, file)
 		}
-		fmt.Println("✓ Created .ralph/prompt.md")
 	}
 
-	// Create skills based on tool
-	var skillsBaseDir string
-	switch tool {
-	case "copilot":
-		skillsBaseDir = filepath.Join(workDir, ".github", "skills")
-	case "claude":
-		skillsBaseDir = filepath.Join(workDir, ".claude", "skills")
+	// Keep run state and personal config out of the agent's commits
+	gitignore := initFile{filepath.Join(".ralph", ".gitignore"), "", "gitignore"}
+	if gitignore.content, err = readTemplate(templatesDir, gitignore.template); err != nil {
+		return nil, err
+	}
+	files = append(files, gitignore)
+
+	return files, nil
+}
+
+func hasInitFile(files []initFile, path string) bool {
+	for _, file := range files {
+		if file.path == path {
+			return true
+		}
 	}
+	return false
+}
 
-	// Create prd-generator skill
-	prdGenDir := filepath.Join(skillsBaseDir, "prd-generator")
-	if err := os.MkdirAll(prdGenDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating prd-generator skill directory: %v\n", err)
+func runInit(tools []string, opts InitOptions) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting current directory: %v\n", err)
 		os.Exit(1)
 	}
 
-	prdGenPath := filepath.Join(prdGenDir, "SKILL.md")
-	if !promptOverwrite(prdGenPath) {
-		fmt.Println("Skipped prd-generator/SKILL.md")
+	if opts.DryRun {
+		fmt.Printf("Planned changes to initialize Ralph for tool: %s\n\n", strings.Join(tools, ", "))
 	} else {
-		if err := writeFile(prdGenPath, prdGeneratorSkill); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing prd-generator/SKILL.md: %v\n", err)
-			os.Exit(1)
-		}
-		relPath := strings.TrimPrefix(prdGenPath, workDir+"/")
-		fmt.Printf("✓ Created %s\n", relPath)
+		fmt.Printf("Initializing Ralph for tool: %s\n\n", strings.Join(tools, ", "))
 	}
 
-	// Create prd-converter skill
-	prdConvDir := filepath.Join(skillsBaseDir, "prd-converter")
-	if err := os.MkdirAll(prdConvDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating prd-converter skill directory: %v\n", err)
+	files, err := initFiles(tools, initPromptFile(filepath.Join(workDir, ".ralph"), tools), opts.TemplatesDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading templates: %v\n", err)
 		os.Exit(1)
 	}
+	if opts.TemplatesDir != "" {
+		fmt.Printf("Using templates from %s\n\n", opts.TemplatesDir)
+	}
 
-	prdConvPath := filepath.Join(prdConvDir, "SKILL.md")
-	if !promptOverwrite(prdConvPath) {
-		fmt.Println("Skipped prd-converter/SKILL.md")
-	} else {
-		if err := writeFile(prdConvPath, prdConverterSkill); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing prd-converter/SKILL.md: %v\n", err)
+	var written []initFile
+	for _, file := range files {
+		path := filepath.Join(workDir, file.path)
+
+		write, err := shouldWrite(path, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s %v\n", file.path, err)
+			os.Exit(1)
+		}
+
+		if opts.DryRun {
+			fmt.Printf("%-10s %s\n", plannedAction(path, opts), file.path)
+			continue
+		}
+		if !write {
+			fmt.Printf("Skipped %s\n", file.path)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s directory: %v\n", filepath.Dir(file.path), err)
+			os.Exit(1)
+		}
+		if err := writeFile(path, file.content); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", file.path, err)
 			os.Exit(1)
 		}
-		relPath := strings.TrimPrefix(prdConvPath, workDir+"/")
-		fmt.Printf("✓ Created %s\n", relPath)
+		fmt.Printf("✓ Created %s\n", file.path)
+		written = append(written, file)
+	}
+
+	if opts.DryRun {
+		return
+	}
+	if err := recordTemplates(workDir, tools, written); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record templates for later upgrades: %v\n", err)
 	}
 
 	fmt.Println("\n✅ Ralph initialization complete!")
@@ -298,11 +545,44 @@ func runInit(tool string) {
 	fmt.Println("2. Run: go-ralph")
 }
 
-func promptOverwrite(path string) bool {
-	if !fileExists(path) {
-		return true
+// shouldWrite decides whether init writes path. Existing files are only
+// overwritten with --force or after confirming on an interactive terminal.
+func shouldWrite(path string, opts InitOptions) (bool, error) {
+	if !fileExists(path) || opts.Force {
+		return true, nil
+	}
+	if opts.SkipExisting || opts.DryRun {
+		return false, nil
+	}
+	if !stdinIsTerminal() {
+		return false, errors.New("already exists and stdin is not a terminal, use --force to overwrite or --skip-existing to keep it")
+	}
+	return promptOverwrite(path), nil
+}
+
+// plannedAction describes what init would do with path, matching shouldWrite:
+// an existing file without --force or --skip-existing is only asked about on
+// a terminal and fails otherwise.
+func plannedAction(path string, opts InitOptions) string {
+	switch {
+	case !fileExists(path):
+		return "create"
+	case opts.Force:
+		return "overwrite"
+	case opts.SkipExisting:
+		return "skip"
+	case !stdinIsTerminal():
+		return "fail"
+	default:
+		return "ask"
 	}
+}
 
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+func promptOverwrite(path string) bool {
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Printf("%s already exists. Overwrite? (y/n): ", filepath.Base(path))
 	response, err := reader.ReadString('\n')
@@ -314,14 +594,16 @@ func promptOverwrite(path string) bool {
 	return response == "y" || response == "yes"
 }
 
-func loadConfig(path string) (*Config, error) {
+func loadConfig(path string) (*ralph.Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	var config ralph.Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && err != io.EOF {
 		return nil, err
 	}
 
@@ -354,48 +636,10 @@ func copyFile(src, dst string) error {
 }
 
 func getBranchFromPRD(prdFile string) string {
-	data, err := os.ReadFile(prdFile)
+	prd, err := ralph.LoadPRD(prdFile)
 	if err != nil {
 		return ""
 	}
 
-	var prd PRD
-	if err := yaml.Unmarshal(data, &prd); err != nil {
-		return ""
-	}
-
 	return prd.BranchName
 }
-
-func initProgressFile(path string) {
-	content := fmt.Sprintf("# Ralph Progress Log\nStarted: %s\n---\n", time.Now().Format(time.RFC1123))
-	writeFile(path, content)
-}
-
-func runToolWithInput(ralphDir, tool string, args []string, inputFile string) (string, error) {
-	inputPath := filepath.Join(ralphDir, inputFile)
-
-	// Read input file
-	input, err := os.ReadFile(inputPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", inputFile, err)
-		return "", err
-	}
-
-	// Create command
-	cmd := exec.Command(tool, args...)
-	cmd.Stdin = bytes.NewReader(input)
-
-	// Capture output while displaying it (tee behavior)
-	var outputBuf bytes.Buffer
-	multiWriter := io.MultiWriter(os.Stdout, &outputBuf)
-	multiErrWriter := io.MultiWriter(os.Stderr, &outputBuf)
-
-	cmd.Stdout = multiWriter
-	cmd.Stderr = multiErrWriter
-
-	// Run command
-	err = cmd.Run()
-
-	return outputBuf.String(), err
-}
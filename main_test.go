@@ -2,11 +2,13 @@ package main
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/jlucaspains/go-ralph/ralph"
 	"gopkg.in/yaml.v3"
 )
 
@@ -179,7 +181,7 @@ func TestGetBranchFromPRD(t *testing.T) {
 		tmpDir := t.TempDir()
 		prdFile := filepath.Join(tmpDir, "prd.yaml")
 
-		prd := PRD{
+		prd := ralph.PRD{
 			Project:     "Test Project",
 			BranchName:  "feature/test-branch",
 			Description: "Test description",
@@ -226,7 +228,7 @@ func TestInitProgressFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	progressFile := filepath.Join(tmpDir, "progress.txt")
 
-	initProgressFile(progressFile)
+	ralph.InitProgressFile(progressFile)
 
 	data, err := os.ReadFile(progressFile)
 	if err != nil {
@@ -247,7 +249,7 @@ func TestInitProgressFile(t *testing.T) {
 
 func TestConfigStruct(t *testing.T) {
 	t.Run("marshal and unmarshal", func(t *testing.T) {
-		config := Config{
+		config := ralph.Config{
 			Tool:          "copilot",
 			MaxIterations: 10,
 			AutoArchive:   false,
@@ -262,7 +264,7 @@ func TestConfigStruct(t *testing.T) {
 			t.Fatalf("Failed to marshal config: %v", err)
 		}
 
-		var unmarshaled Config
+		var unmarshaled ralph.Config
 		if err := yaml.Unmarshal(data, &unmarshaled); err != nil {
 			t.Fatalf("Failed to unmarshal config: %v", err)
 		}
@@ -284,11 +286,11 @@ func TestConfigStruct(t *testing.T) {
 
 func TestPRDStruct(t *testing.T) {
 	t.Run("marshal and unmarshal", func(t *testing.T) {
-		prd := PRD{
+		prd := ralph.PRD{
 			Project:     "Test Project",
 			BranchName:  "feature/test",
 			Description: "Test description",
-			UserStories: []UserStory{
+			UserStories: []ralph.UserStory{
 				{
 					ID:          "US-1",
 					Title:       "Test Story",
@@ -309,7 +311,7 @@ func TestPRDStruct(t *testing.T) {
 			t.Fatalf("Failed to marshal PRD: %v", err)
 		}
 
-		var unmarshaled PRD
+		var unmarshaled ralph.PRD
 		if err := yaml.Unmarshal(data, &unmarshaled); err != nil {
 			t.Fatalf("Failed to unmarshal PRD: %v", err)
 		}
@@ -330,7 +332,7 @@ func TestPRDStruct(t *testing.T) {
 }
 
 func TestUserStoryStruct(t *testing.T) {
-	story := UserStory{
+	story := ralph.UserStory{
 		ID:          "US-123",
 		Title:       "Test Title",
 		Description: "Test Description",
@@ -349,7 +351,7 @@ func TestUserStoryStruct(t *testing.T) {
 		t.Fatalf("Failed to marshal UserStory: %v", err)
 	}
 
-	var unmarshaled UserStory
+	var unmarshaled ralph.UserStory
 	if err := yaml.Unmarshal(data, &unmarshaled); err != nil {
 		t.Fatalf("Failed to unmarshal UserStory: %v", err)
 	}
@@ -403,16 +405,6 @@ func TestEmbeddedTemplates(t *testing.T) {
 	})
 }
 
-func TestRunToolWithInputError(t *testing.T) {
-	tmpDir := t.TempDir()
-
-	// Test with non-existent input file
-	_, err := runToolWithInput(tmpDir, "echo", []string{}, "nonexistent.txt")
-	if err == nil {
-		t.Error("Expected error when input file doesn't exist")
-	}
-}
-
 func TestProgressFileContent(t *testing.T) {
 	tmpDir := t.TempDir()
 	progressFile := filepath.Join(tmpDir, "progress.txt")
@@ -420,7 +412,7 @@ func TestProgressFileContent(t *testing.T) {
 	// Record the time before creating the file
 	beforeTime := time.Now()
 
-	initProgressFile(progressFile)
+	ralph.InitProgressFile(progressFile)
 
 	data, err := os.ReadFile(progressFile)
 	if err != nil {
@@ -453,3 +445,202 @@ func TestProgressFileContent(t *testing.T) {
 		t.Error("Progress file creation took too long")
 	}
 }
+
+func TestInitFiles(t *testing.T) {
+	t.Run("claude", func(t *testing.T) {
+		files, err := initFiles([]string{"claude"}, "prompt.md", "")
+		if err != nil {
+			t.Fatalf("initFiles failed: %v", err)
+		}
+		if len(files) != 5 {
+			t.Fatalf("Expected 5 files, got %d", len(files))
+		}
+		if !strings.Contains(files[0].content, "tool: claude") {
+			t.Error("Expected config to use the claude tool")
+		}
+		if files[1].content != claudePrompt {
+			t.Error("Expected claude prompt")
+		}
+		if files[2].path != filepath.Join(".claude", "skills", "prd-generator", "SKILL.md") {
+			t.Errorf("Unexpected skill path: %s", files[2].path)
+		}
+	})
+
+	t.Run("copilot", func(t *testing.T) {
+		files, err := initFiles([]string{"copilot"}, "prompt.md", "")
+		if err != nil {
+			t.Fatalf("initFiles failed: %v", err)
+		}
+		if files[1].content != copilotPrompt {
+			t.Error("Expected copilot prompt")
+		}
+		if files[3].path != filepath.Join(".github", "skills", "prd-converter", "SKILL.md") {
+			t.Errorf("Unexpected skill path: %s", files[3].path)
+		}
+	})
+
+	t.Run("claude and copilot", func(t *testing.T) {
+		files, err := initFiles([]string{"claude", "copilot"}, "prompt.{tool}.md", "")
+		if err != nil {
+			t.Fatalf("initFiles failed: %v", err)
+		}
+		if len(files) != 8 {
+			t.Fatalf("Expected 8 files, got %d", len(files))
+		}
+		if !strings.Contains(files[0].content, "tool: claude") || !strings.Contains(files[0].content, "prompt_file: prompt.{tool}.md") {
+			t.Errorf("Expected config with per-tool prompt file, got:\n%s", files[0].content)
+		}
+		if files[1].path != filepath.Join(".ralph", "prompt.claude.md") || files[1].content != claudePrompt {
+			t.Errorf("Unexpected claude prompt file: %s", files[1].path)
+		}
+		if files[4].path != filepath.Join(".ralph", "prompt.copilot.md") || files[4].content != copilotPrompt {
+			t.Errorf("Unexpected copilot prompt file: %s", files[4].path)
+		}
+		if files[5].path != filepath.Join(".github", "skills", "prd-generator", "SKILL.md") {
+			t.Errorf("Unexpected skill path: %s", files[5].path)
+		}
+		if files[7].path != filepath.Join(".ralph", ".gitignore") || files[7].content != gitignoreTemplate {
+			t.Errorf("Expected the .ralph/.gitignore last, got %s", files[7].path)
+		}
+	})
+
+	t.Run("shared prompt file", func(t *testing.T) {
+		files, err := initFiles([]string{"claude", "copilot"}, "prompt.md", "")
+		if err != nil {
+			t.Fatalf("initFiles failed: %v", err)
+		}
+		if len(files) != 7 {
+			t.Fatalf("Expected 7 files, got %d", len(files))
+		}
+		if files[1].path != filepath.Join(".ralph", "prompt.md") || files[1].content != claudePrompt {
+			t.Errorf("Expected the claude prompt in prompt.md, got %s", files[1].path)
+		}
+		for _, file := range files[2:] {
+			if file.path == files[1].path {
+				t.Errorf("Expected prompt.md once, got it again from %s", file.template)
+			}
+		}
+	})
+}
+
+func TestInitPromptFile(t *testing.T) {
+	ralphDir := t.TempDir()
+	if got := initPromptFile(ralphDir, []string{"claude"}); got != "prompt.md" {
+		t.Errorf("Expected prompt.md for one tool, got %s", got)
+	}
+	if got := initPromptFile(ralphDir, []string{"claude", "copilot"}); got != "prompt.{tool}.md" {
+		t.Errorf("Expected prompt.{tool}.md for several tools, got %s", got)
+	}
+
+	os.WriteFile(filepath.Join(ralphDir, "config.yaml"), []byte("tool: claude\nprompt_file: prompt.md\n"), 0644)
+	if got := initPromptFile(ralphDir, []string{"claude", "copilot"}); got != "prompt.md" {
+		t.Errorf("Expected the config's prompt_file, got %s", got)
+	}
+}
+
+func TestRalphGitignore(t *testing.T) {
+	dir := initTestRepo(t)
+	ralphDir := filepath.Join(dir, ".ralph")
+	for _, file := range []string{
+		".gitignore", "config.yaml", "prd.yaml", "progress.txt", "prompt.md",
+		"templates.yaml", "templates/claude/prompt.md",
+		"config.local.yaml", "ralph.lock", ".last-branch", "prd.yaml.v0.bak",
+		"runs/20260101-120000/journal.jsonl", "worktrees/US-001/README.md",
+	} {
+		os.MkdirAll(filepath.Dir(filepath.Join(ralphDir, file)), 0755)
+		os.WriteFile(filepath.Join(ralphDir, file), []byte("x\n"), 0644)
+	}
+	os.WriteFile(filepath.Join(ralphDir, ".gitignore"), []byte(gitignoreTemplate), 0644)
+
+	cmd := exec.Command("git", "status", "--porcelain", "--untracked-files=all")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git status failed: %v", err)
+	}
+	expected := "?? .ralph/.gitignore\n?? .ralph/config.yaml\n?? .ralph/prd.yaml\n?? .ralph/progress.txt\n?? .ralph/prompt.md\n" +
+		"?? .ralph/templates.yaml\n?? .ralph/templates/claude/prompt.md\n"
+	if string(out) != expected {
+		t.Errorf("Expected only the shared files to be untracked, got:\n%s", out)
+	}
+}
+
+func TestParseTools(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"claude", "claude"},
+		{"claude,copilot", "claude,copilot"},
+		{"copilot, claude, copilot", "copilot,claude"},
+		{"all", "claude,copilot"},
+	}
+	for _, tt := range tests {
+		tools, err := parseTools(tt.value)
+		if err != nil {
+			t.Errorf("parseTools(%q) failed: %v", tt.value, err)
+		}
+		if strings.Join(tools, ",") != tt.expected {
+			t.Errorf("parseTools(%q) = %v, expected %s", tt.value, tools, tt.expected)
+		}
+	}
+
+	if _, err := parseTools("claude,cursor"); err == nil {
+		t.Error("Expected error for unknown tool")
+	}
+}
+
+func TestShouldWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	existing := filepath.Join(tmpDir, "config.yaml")
+	os.WriteFile(existing, []byte("tool: claude\n"), 0644)
+	missing := filepath.Join(tmpDir, "prompt.md")
+
+	if write, err := shouldWrite(missing, InitOptions{}); !write || err != nil {
+		t.Errorf("Expected missing file to be written, got %v, %v", write, err)
+	}
+	if write, err := shouldWrite(existing, InitOptions{Force: true}); !write || err != nil {
+		t.Errorf("Expected --force to overwrite, got %v, %v", write, err)
+	}
+	if write, err := shouldWrite(existing, InitOptions{SkipExisting: true}); write || err != nil {
+		t.Errorf("Expected --skip-existing to keep the file, got %v, %v", write, err)
+	}
+	if write, err := shouldWrite(existing, InitOptions{DryRun: true}); write || err != nil {
+		t.Errorf("Expected --dry-run not to write, got %v, %v", write, err)
+	}
+
+	if !stdinIsTerminal() {
+		_, err := shouldWrite(existing, InitOptions{})
+		if err == nil || !strings.Contains(err.Error(), "stdin is not a terminal") {
+			t.Errorf("Expected non-terminal error, got: %v", err)
+		}
+	}
+}
+
+func TestPlannedAction(t *testing.T) {
+	tmpDir := t.TempDir()
+	existing := filepath.Join(tmpDir, "config.yaml")
+	os.WriteFile(existing, []byte("tool: claude\n"), 0644)
+
+	// Without a terminal to ask on, the real init fails on existing files
+	unattended := "ask"
+	if !stdinIsTerminal() {
+		unattended = "fail"
+	}
+
+	tests := []struct {
+		path     string
+		opts     InitOptions
+		expected string
+	}{
+		{filepath.Join(tmpDir, "missing.md"), InitOptions{}, "create"},
+		{existing, InitOptions{Force: true}, "overwrite"},
+		{existing, InitOptions{SkipExisting: true}, "skip"},
+		{existing, InitOptions{}, unattended},
+	}
+	for _, tt := range tests {
+		if action := plannedAction(tt.path, tt.opts); action != tt.expected {
+			t.Errorf("plannedAction(%s, %+v) = %s, expected %s", filepath.Base(tt.path), tt.opts, action, tt.expected)
+		}
+	}
+}
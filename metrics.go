@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+// iterationBuckets are the upper bounds in seconds of the iteration duration
+// histogram. Agent iterations take minutes rather than milliseconds.
+var iterationBuckets = []float64{30, 60, 120, 300, 600, 1200, 1800, 3600}
+
+// metrics counts run events for Prometheus, served in the text exposition
+// format at /metrics.
+type metrics struct {
+	labels string
+
+	mu            sync.Mutex
+	iterations    int
+	buckets       []int
+	durationSum   float64
+	storiesPassed int
+	rejected      int
+	exitCodes     map[int]int
+	runs          map[ralph.StopReason]int
+	// tokens is nil until an iteration's tool reports its usage
+	tokens map[string]int
+}
+
+func newMetrics(project, tool string) *metrics {
+	return &metrics{
+		labels:    fmt.Sprintf("project=%s,tool=%s", labelValue(project), labelValue(tool)),
+		buckets:   make([]int, len(iterationBuckets)),
+		exitCodes: map[int]int{},
+		runs:      map[ralph.StopReason]int{},
+	}
+}
+
+// labelValue quotes a Prometheus label value.
+func labelValue(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// Observe updates the metrics from a run event.
+func (m *metrics) Observe(event ralph.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch event.Type {
+	case ralph.EventIterationFinished:
+		m.iterations++
+		seconds := event.Record.Duration.Seconds()
+		m.durationSum += seconds
+		for i, bound := range iterationBuckets {
+			if seconds <= bound {
+				m.buckets[i]++
+			}
+		}
+		m.exitCodes[event.Record.ExitCode]++
+		if usage := event.Record.Usage; usage != nil {
+			if m.tokens == nil {
+				m.tokens = map[string]int{}
+			}
+			m.tokens["input"] += usage.InputTokens
+			m.tokens["output"] += usage.OutputTokens
+			m.tokens["cache_creation"] += usage.CacheCreationInputTokens
+			m.tokens["cache_read"] += usage.CacheReadInputTokens
+		}
+	case ralph.EventStoryPassed:
+		m.storiesPassed += len(event.Stories)
+	case ralph.EventStoryRejected:
+		m.rejected += len(event.Stories)
+	case ralph.EventRunFinished:
+		m.runs[event.Result.Reason]++
+	}
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	header := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	header("ralph_iterations_total", "counter", "Agent iterations run.")
+	fmt.Fprintf(&b, "ralph_iterations_total{%s} %d\n", m.labels, m.iterations)
+
+	header("ralph_iteration_duration_seconds", "histogram", "Duration of agent iterations.")
+	for i, bound := range iterationBuckets {
+		fmt.Fprintf(&b, "ralph_iteration_duration_seconds_bucket{%s,le=\"%s\"} %d\n", m.labels, strconv.FormatFloat(bound, 'f', -1, 64), m.buckets[i])
+	}
+	fmt.Fprintf(&b, "ralph_iteration_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", m.labels, m.iterations)
+	fmt.Fprintf(&b, "ralph_iteration_duration_seconds_sum{%s} %s\n", m.labels, strconv.FormatFloat(m.durationSum, 'f', -1, 64))
+	fmt.Fprintf(&b, "ralph_iteration_duration_seconds_count{%s} %d\n", m.labels, m.iterations)
+
+	header("ralph_stories_passed_total", "counter", "Stories that started passing.")
+	fmt.Fprintf(&b, "ralph_stories_passed_total{%s} %d\n", m.labels, m.storiesPassed)
+
+	header("ralph_gate_failures_total", "counter", "Stories held back by a gate, such as a rejected approval.")
+	fmt.Fprintf(&b, "ralph_gate_failures_total{%s,gate=\"approval\"} %d\n", m.labels, m.rejected)
+
+	header("ralph_tool_exit_codes_total", "counter", "Agent tool exits by exit code.")
+	codes := make([]int, 0, len(m.exitCodes))
+	for code := range m.exitCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(&b, "ralph_tool_exit_codes_total{%s,code=\"%d\"} %d\n", m.labels, code, m.exitCodes[code])
+	}
+
+	header("ralph_tokens_total", "counter", "Tokens consumed by the agent tool, by type. Only tools that report usage are counted.")
+	for _, kind := range []string{"input", "output", "cache_creation", "cache_read"} {
+		if count, ok := m.tokens[kind]; ok {
+			fmt.Fprintf(&b, "ralph_tokens_total{%s,type=\"%s\"} %d\n", m.labels, kind, count)
+		}
+	}
+
+	header("ralph_runs_total", "counter", "Finished runs by the reason they stopped.")
+	reasons := make([]string, 0, len(m.runs))
+	for reason := range m.runs {
+		reasons = append(reasons, string(reason))
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(&b, "ralph_runs_total{%s,reason=%s} %d\n", m.labels, labelValue(reason), m.runs[ralph.StopReason(reason)])
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (m *metrics) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m)
+	return mux
+}
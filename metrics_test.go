@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+func TestMetrics(t *testing.T) {
+	m := newMetrics(`My "app"`, "claude")
+	m.Observe(ralph.Event{Type: ralph.EventIterationFinished, Record: &ralph.IterationRecord{Duration: 45 * time.Second}})
+	m.Observe(ralph.Event{Type: ralph.EventIterationFinished, Record: &ralph.IterationRecord{Duration: 10 * time.Minute, ExitCode: 1,
+		Usage: &ralph.Usage{InputTokens: 1200, OutputTokens: 300, CacheReadInputTokens: 5000}}})
+	m.Observe(ralph.Event{Type: ralph.EventStoryPassed, Stories: []string{"US-001"}})
+	m.Observe(ralph.Event{Type: ralph.EventStoryRejected, Stories: []string{"US-002"}})
+	m.Observe(ralph.Event{Type: ralph.EventRunFinished, Result: &ralph.Result{Reason: ralph.StopMaxIterations}})
+
+	var out strings.Builder
+	m.WriteTo(&out)
+	labels := `project="My \"app\"",tool="claude"`
+	for _, want := range []string{
+		"# TYPE ralph_iterations_total counter",
+		"ralph_iterations_total{" + labels + "} 2",
+		"# TYPE ralph_iteration_duration_seconds histogram",
+		"ralph_iteration_duration_seconds_bucket{" + labels + `,le="30"} 0`,
+		"ralph_iteration_duration_seconds_bucket{" + labels + `,le="60"} 1`,
+		"ralph_iteration_duration_seconds_bucket{" + labels + `,le="600"} 2`,
+		"ralph_iteration_duration_seconds_bucket{" + labels + `,le="+Inf"} 2`,
+		"ralph_iteration_duration_seconds_sum{" + labels + "} 645",
+		"ralph_iteration_duration_seconds_count{" + labels + "} 2",
+		"ralph_stories_passed_total{" + labels + "} 1",
+		"ralph_gate_failures_total{" + labels + `,gate="approval"} 1`,
+		"ralph_tool_exit_codes_total{" + labels + `,code="0"} 1`,
+		"ralph_tool_exit_codes_total{" + labels + `,code="1"} 1`,
+		"ralph_runs_total{" + labels + `,reason="max_iterations"} 1`,
+		"# TYPE ralph_tokens_total counter",
+		"ralph_tokens_total{" + labels + `,type="input"} 1200`,
+		"ralph_tokens_total{" + labels + `,type="output"} 300`,
+		"ralph_tokens_total{" + labels + `,type="cache_creation"} 0`,
+		"ralph_tokens_total{" + labels + `,type="cache_read"} 5000`,
+	} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url, err := serveBackground(ctx, "127.0.0.1:0", newMetrics("Test", "copilot").handler())
+	if err != nil {
+		t.Fatalf("serveBackground failed: %v", err)
+	}
+
+	resp, err := http.Get(url + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") || !strings.Contains(string(body), `ralph_iterations_total{project="Test",tool="copilot"} 0`) {
+		t.Errorf("Unexpected response %s:\n%s", resp.Header.Get("Content-Type"), body)
+	}
+	if strings.Contains(string(body), "ralph_tokens_total{") {
+		t.Errorf("Expected no token series before a tool reports usage, got:\n%s", body)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// migration upgrades a YAML document to version. Migrations work on the
+// document's root mapping node so comments and key order survive. Those
+// without apply only bump the version, which is patched into the text so the
+// file keeps its formatting too.
+type migration struct {
+	version     int
+	description string
+	apply       func(root *yaml.Node) error
+}
+
+// Migrations for each document kind, in version order. The last entry's
+// version is the current schema version.
+var configMigrations = []migration{
+	{version: 1, description: "add schema version"},
+}
+
+var prdMigrations = []migration{
+	{version: 1, description: "add schema version"},
+}
+
+func latestVersion(migrations []migration) int {
+	return migrations[len(migrations)-1].version
+}
+
+// MigrationResult describes what migrateFile did to a document.
+type MigrationResult struct {
+	Path   string
+	From   int
+	To     int
+	Backup string
+}
+
+func (r MigrationResult) Migrated() bool {
+	return r.From != r.To
+}
+
+// migrateFile upgrades the YAML document at path to the latest version in
+// migrations, keeping a copy of the original next to it. Missing or empty
+// files are left alone.
+func migrateFile(path string, migrations []migration) (MigrationResult, error) {
+	result := MigrationResult{Path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return result, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return result, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return result, fmt.Errorf("%s: expected a mapping at the top level", path)
+	}
+
+	version, err := documentVersion(root)
+	if err != nil {
+		return result, fmt.Errorf("%s: %w", path, err)
+	}
+	result.From, result.To = version, version
+
+	latest := latestVersion(migrations)
+	if version > latest {
+		return result, fmt.Errorf("%s: version %d is newer than this go-ralph supports (%d), upgrade go-ralph", path, version, latest)
+	}
+	if version == latest {
+		return result, nil
+	}
+
+	changed := false
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		if m.apply != nil {
+			if err := m.apply(root); err != nil {
+				return result, fmt.Errorf("%s: migration to version %d (%s) failed: %w", path, m.version, m.description, err)
+			}
+			changed = true
+		}
+		result.To = m.version
+	}
+
+	var migrated []byte
+	patched := false
+	if !changed {
+		migrated, patched = patchDocumentVersion(data, root, result.To)
+	}
+	if !patched {
+		setDocumentVersion(root, result.To)
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&doc); err != nil {
+			return result, err
+		}
+		encoder.Close()
+		migrated = buf.Bytes()
+	}
+
+	result.Backup = fmt.Sprintf("%s.v%d.bak", path, result.From)
+	if err := copyFile(path, result.Backup); err != nil {
+		return result, fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, migrated, 0644); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// documentVersion reads the top-level version key, 0 when it is missing.
+func documentVersion(root *yaml.Node) (int, error) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "version" {
+			version, err := strconv.Atoi(root.Content[i+1].Value)
+			if err != nil {
+				return 0, fmt.Errorf("invalid version '%s'", root.Content[i+1].Value)
+			}
+			return version, nil
+		}
+	}
+	return 0, nil
+}
+
+// setDocumentVersion updates the version key, adding it as the first key when
+// it is missing.
+func setDocumentVersion(root *yaml.Node, version int) {
+	value := strconv.Itoa(version)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "version" {
+			root.Content[i+1].Value = value
+			return
+		}
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	val := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: value}
+	root.Content = append([]*yaml.Node{key, val}, root.Content...)
+}
+
+// patchDocumentVersion writes version into data, the text root was parsed
+// from, without re-encoding the rest. A missing version key goes above the
+// first key, below the comments at the top of the file. It reports false when
+// the version can't be patched, e.g. in a flow style mapping.
+func patchDocumentVersion(data []byte, root *yaml.Node, version int) ([]byte, bool) {
+	if root.Style&yaml.FlowStyle != 0 || len(root.Content) == 0 {
+		return nil, false
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	value := strconv.Itoa(version)
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "version" {
+			continue
+		}
+		old := root.Content[i+1]
+		if old.Style != 0 || old.Line < 1 || old.Line > len(lines) {
+			return nil, false
+		}
+		line := lines[old.Line-1]
+		start := old.Column - 1
+		if start > len(line) || !strings.HasPrefix(line[start:], old.Value) {
+			return nil, false
+		}
+		lines[old.Line-1] = line[:start] + value + line[start+len(old.Value):]
+		return []byte(strings.Join(lines, "")), true
+	}
+
+	first := root.Content[0]
+	if first.Line < 1 || first.Line > len(lines) {
+		return nil, false
+	}
+	lines[first.Line-1] = strings.Repeat(" ", first.Column-1) + "version: " + value + "\n" + lines[first.Line-1]
+	return []byte(strings.Join(lines, "")), true
+}
+
+type versionedFile struct {
+	path       string
+	migrations []migration
+}
+
+// versionedFiles lists the config files and the PRD for ralphDir.
+func versionedFiles(ralphDir string) []versionedFile {
+	return []versionedFile{
+		{globalConfigPath(), configMigrations},
+		{filepath.Join(ralphDir, "config.yaml"), configMigrations},
+		{filepath.Join(ralphDir, "config.local.yaml"), configMigrations},
+		{filepath.Join(ralphDir, "prd.yaml"), prdMigrations},
+	}
+}
+
+// fileVersion reads the version of the YAML document at path, 0 when the
+// file or its version key is missing.
+func fileVersion(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return 0, nil
+	}
+	return documentVersion(doc.Content[0])
+}
+
+// migrateRalphFiles upgrades every config file and the PRD for ralphDir.
+func migrateRalphFiles(ralphDir string) ([]MigrationResult, error) {
+	var results []MigrationResult
+	for _, file := range versionedFiles(ralphDir) {
+		if file.path == "" {
+			continue
+		}
+		result, err := migrateFile(file.path, file.migrations)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func printMigrationResults(results []MigrationResult, verbose bool) {
+	for _, result := range results {
+		if result.Migrated() {
+			fmt.Printf("Migrated %s from version %d to %d (backup: %s)\n", result.Path, result.From, result.To, filepath.Base(result.Backup))
+		} else if verbose && fileExists(result.Path) {
+			fmt.Printf("%s is up to date (version %d)\n", result.Path, result.To)
+		}
+	}
+}
+
+// runMigrate implements `go-ralph migrate`.
+func runMigrate(args []string) {
+	fs := newFlagSet("migrate", "Upgrade the global, repo and local config files and the PRD to the current schema version, keeping a backup of each.")
+	fs.Parse(args)
+
+	_, ralphDir := ralphDirs()
+	results, err := migrateRalphFiles(ralphDir)
+	printMigrationResults(results, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrateFile(t *testing.T) {
+	t.Run("upgrades unversioned document", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		original := "# team settings\ntool: claude\nmax_iterations: 10\ntool_args:\n  claude:\n    - \"--print\"\n"
+		os.WriteFile(path, []byte(original), 0644)
+
+		result, err := migrateFile(path, configMigrations)
+		if err != nil {
+			t.Fatalf("migrateFile failed: %v", err)
+		}
+		if !result.Migrated() || result.From != 0 || result.To != latestVersion(configMigrations) {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+
+		if readFile(result.Backup) != strings.TrimSpace(original) {
+			t.Error("Expected backup to contain the original file")
+		}
+
+		data, _ := os.ReadFile(path)
+		if expected := "# team settings\nversion: 1\n" + strings.TrimPrefix(original, "# team settings\n"); string(data) != expected {
+			t.Errorf("Expected version as first key and the rest untouched, got:\n%s", data)
+		}
+
+		config, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("Migrated config failed to load: %v", err)
+		}
+		if config.Version != 1 || config.Tool != "claude" || config.ToolArgs["claude"][0] != "--print" {
+			t.Errorf("Unexpected migrated config: %+v", config)
+		}
+	})
+
+	t.Run("keeps formatting", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "prd.yaml")
+		original := "project: Test\nuserStories:\n- id: US-1 # first\n  acceptanceCriteria:\n  - Works\n"
+		os.WriteFile(path, []byte(original), 0644)
+
+		if _, err := migrateFile(path, prdMigrations); err != nil {
+			t.Fatalf("migrateFile failed: %v", err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "version: 1\n"+original {
+			t.Errorf("Expected only the version to be added, got:\n%s", data)
+		}
+	})
+
+	t.Run("bumps existing version", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "prd.yaml")
+		os.WriteFile(path, []byte("# PRD\nversion: 0 # schema\nproject: Test\n"), 0644)
+
+		if _, err := migrateFile(path, prdMigrations); err != nil {
+			t.Fatalf("migrateFile failed: %v", err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "# PRD\nversion: 1 # schema\nproject: Test\n" {
+			t.Errorf("Expected only the version to change, got:\n%s", data)
+		}
+	})
+
+	t.Run("current document is left alone", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "prd.yaml")
+		os.WriteFile(path, []byte("version: 1\nproject: Test\n"), 0644)
+
+		result, err := migrateFile(path, prdMigrations)
+		if err != nil {
+			t.Fatalf("migrateFile failed: %v", err)
+		}
+		if result.Migrated() || result.Backup != "" {
+			t.Errorf("Expected no migration, got %+v", result)
+		}
+	})
+
+	t.Run("newer document is rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "prd.yaml")
+		os.WriteFile(path, []byte("version: 99\nproject: Test\n"), 0644)
+
+		if _, err := migrateFile(path, prdMigrations); err == nil {
+			t.Error("Expected error for newer version")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		result, err := migrateFile(filepath.Join(t.TempDir(), "prd.yaml"), prdMigrations)
+		if err != nil || result.Migrated() {
+			t.Errorf("Expected missing file to be skipped, got %+v, %v", result, err)
+		}
+	})
+}
+
+func TestMigrationsRunInOrder(t *testing.T) {
+	var applied []int
+	migrations := []migration{
+		{version: 1, apply: func(root *yaml.Node) error { applied = append(applied, 1); return nil }},
+		{version: 2, apply: func(root *yaml.Node) error { applied = append(applied, 2); return nil }},
+		{version: 3, apply: func(root *yaml.Node) error { applied = append(applied, 3); return nil }},
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("version: 1\ntool: claude\n"), 0644)
+
+	result, err := migrateFile(path, migrations)
+	if err != nil {
+		t.Fatalf("migrateFile failed: %v", err)
+	}
+	if len(applied) != 2 || applied[0] != 2 || applied[1] != 3 {
+		t.Errorf("Expected migrations 2 and 3 to run, got %v", applied)
+	}
+	if result.To != 3 || !strings.HasPrefix(readFile(path), "version: 3") {
+		t.Errorf("Expected version 3, got %d", result.To)
+	}
+	if filepath.Base(result.Backup) != "config.yaml.v1.bak" {
+		t.Errorf("Unexpected backup name: %s", result.Backup)
+	}
+}
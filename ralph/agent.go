@@ -0,0 +1,70 @@
+package ralph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// Agent runs one iteration of a coding agent in dir with prompt on stdin,
+// streaming its output to stdout and stderr. It returns the combined output,
+// which the runner scans for the completion signal.
+type Agent interface {
+	Run(ctx context.Context, dir string, prompt []byte, stdout, stderr io.Writer) (string, error)
+}
+
+// CommandAgent runs an agent CLI such as claude or copilot.
+type CommandAgent struct {
+	Command string
+	Args    []string
+}
+
+func (a CommandAgent) Run(ctx context.Context, dir string, prompt []byte, stdout, stderr io.Writer) (string, error) {
+	cmd := exec.CommandContext(ctx, a.Command, a.Args...)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(prompt)
+
+	// Capture output while displaying it (tee behavior)
+	output := &lockedBuffer{}
+	cmd.Stdout = io.MultiWriter(stdout, output)
+	cmd.Stderr = io.MultiWriter(stderr, output)
+
+	err := cmd.Run()
+
+	return output.String(), err
+}
+
+// lockedBuffer is a bytes.Buffer safe for the concurrent writes of a
+// command's stdout and stderr.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// exitCode maps an agent error to the exit code recorded in the journal.
+// Errors other than a non-zero exit count as -1.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
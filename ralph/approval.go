@@ -0,0 +1,118 @@
+package ralph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Approver reviews a story the agent marked as passing when approval is
+// required. The run waits for the decision before moving on.
+type Approver interface {
+	Approve(ctx context.Context, req ApprovalRequest) (Approval, error)
+}
+
+// ApproverFunc adapts a function to Approver.
+type ApproverFunc func(ctx context.Context, req ApprovalRequest) (Approval, error)
+
+func (f ApproverFunc) Approve(ctx context.Context, req ApprovalRequest) (Approval, error) {
+	return f(ctx, req)
+}
+
+// ApprovalRequest describes a story waiting for approval along with the
+// commits and diff of the iteration that completed it.
+type ApprovalRequest struct {
+	Story     UserStory   `json:"story"`
+	Iteration int         `json:"iteration"`
+	Commits   []GitCommit `json:"commits,omitempty"`
+	Diff      string      `json:"diff,omitempty"`
+}
+
+// Approval is the decision on an ApprovalRequest. The feedback on a rejected
+// story is added to its notes for the agent's next attempt.
+type Approval struct {
+	Approved bool   `json:"approved"`
+	Feedback string `json:"feedback,omitempty"`
+}
+
+// RequiresApproval reports whether a story needs approval once it passes,
+// because of require_approval or the story's requireApproval flag.
+func (c *Config) RequiresApproval(story UserStory) bool {
+	return c.RequireApproval || story.RequireApproval
+}
+
+// reviewStories asks the Approver about the stories that pass now but didn't
+// before, showing the changes between from and to. Rejected stories are
+// reopened with the feedback in their notes and returned.
+func (r *Runner) reviewStories(ctx context.Context, iteration int, prdFile string, before map[string]bool, from, to string) ([]string, error) {
+	prd, err := LoadPRD(prdFile)
+	if err != nil {
+		return nil, nil
+	}
+
+	var rejected []string
+	notes := map[string]string{}
+	err = func() error {
+		for _, story := range prd.UserStories {
+			if !story.Passes || before[story.ID] || !r.Config.RequiresApproval(story) {
+				continue
+			}
+			if r.Approver == nil {
+				return fmt.Errorf("story %s requires approval but the runner has no Approver", story.ID)
+			}
+
+			req := ApprovalRequest{Story: story, Iteration: iteration}
+			if from != "" {
+				req.Commits, _ = commitsBetween(r.WorkDir, from, to)
+				req.Diff, _ = runGit(r.WorkDir, "diff", from, to)
+			}
+			r.emit(Event{Type: EventApprovalRequested, Iteration: iteration, Stories: []string{story.ID}, Message: fmt.Sprintf("Story %s passes, waiting for approval", story.ID)})
+
+			span := r.startSpan("gate approval", r.parentSpan(), map[string]any{"ralph.gate": "approval", "ralph.story_id": story.ID})
+			approval, err := r.Approver.Approve(ctx, req)
+			span.Attributes["ralph.approved"] = approval.Approved
+			r.endSpan(span, err)
+			if err != nil {
+				return fmt.Errorf("approving story %s: %w", story.ID, err)
+			}
+			if approval.Approved {
+				r.emit(Event{Type: EventStoryApproved, Iteration: iteration, Stories: []string{story.ID}, Message: fmt.Sprintf("Story %s approved", story.ID)})
+				continue
+			}
+
+			notes[story.ID] = reviewNote(story.Notes, iteration, approval.Feedback)
+			rejected = append(rejected, story.ID)
+			message := fmt.Sprintf("Story %s rejected, will retry", story.ID)
+			if approval.Feedback != "" {
+				message += ": " + approval.Feedback
+			}
+			r.emit(Event{Type: EventStoryRejected, Iteration: iteration, Stories: []string{story.ID}, Message: message})
+		}
+		return nil
+	}()
+
+	// Reopen the stories rejected so far, also when a later review failed
+	for _, id := range rejected {
+		saveErr := updateStory(prdFile, id, map[string]*yaml.Node{"passes": boolNode(false), "notes": stringNode(notes[id])})
+		if saveErr != nil && err == nil {
+			err = fmt.Errorf("reopening rejected story %s: %w", id, saveErr)
+		}
+	}
+	return rejected, err
+}
+
+// reviewNote appends the feedback on a rejected story to its notes.
+func reviewNote(notes string, iteration int, feedback string) string {
+	note := fmt.Sprintf("Rejected in review after iteration %d", iteration)
+	if feedback = strings.TrimSpace(feedback); feedback != "" {
+		note += ": " + feedback
+	} else {
+		note += "."
+	}
+	if notes = strings.TrimSpace(notes); notes != "" {
+		return notes + "\n" + note
+	}
+	return note
+}
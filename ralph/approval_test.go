@@ -0,0 +1,123 @@
+package ralph
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunnerApproval(t *testing.T) {
+	// The agent commits a file and marks US-1 as passing every iteration
+	passingAgent := func() Agent {
+		calls := 0
+		return agentFunc(func(_ context.Context, dir string, _ []byte, _, _ io.Writer) (string, error) {
+			calls++
+			file := fmt.Sprintf("change-%d.txt", calls)
+			os.WriteFile(filepath.Join(dir, file), []byte("change\n"), 0644)
+			runGit(dir, "add", file)
+			runGit(dir, "commit", "-m", "feat: US-1 "+file)
+			markStoryPassed(filepath.Join(dir, ".ralph", "prd.yaml"), "US-1")
+			return CompleteSignal, nil
+		})
+	}
+
+	t.Run("reject then approve", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		config.RequireApproval = true
+
+		var requests []ApprovalRequest
+		var events []EventType
+		runner := &Runner{
+			Config:  config,
+			WorkDir: dir,
+			Agent:   passingAgent(),
+			Approver: ApproverFunc(func(_ context.Context, req ApprovalRequest) (Approval, error) {
+				requests = append(requests, req)
+				if len(requests) == 1 {
+					return Approval{Feedback: "Add tests"}, nil
+				}
+				return Approval{Approved: true}, nil
+			}),
+			OnEvent: func(e Event) { events = append(events, e.Type) },
+		}
+
+		result, err := runner.Run(context.Background())
+		if err != nil || !result.Complete || len(result.Records) != 2 {
+			t.Fatalf("Expected completion after the second attempt, got %v, %+v", err, result)
+		}
+		if got := result.Records[0].Rejected; len(got) != 1 || got[0] != "US-1" || result.Records[0].Complete {
+			t.Errorf("Expected US-1 rejected in iteration 1, got %+v", result.Records[0])
+		}
+
+		if len(requests) != 2 {
+			t.Fatalf("Expected 2 approval requests, got %d", len(requests))
+		}
+		first := requests[0]
+		if first.Story.ID != "US-1" || len(first.Commits) != 1 || !strings.Contains(first.Commits[0].Subject, "feat: US-1") || !strings.Contains(first.Diff, "+change") {
+			t.Errorf("Unexpected approval request: %+v", first)
+		}
+		if !strings.Contains(requests[1].Story.Notes, "Rejected in review after iteration 1: Add tests") {
+			t.Errorf("Expected the feedback in the story notes, got %q", requests[1].Story.Notes)
+		}
+
+		joined := strings.Join(toStrings(events), ",")
+		if !strings.Contains(joined, "approval_requested,story_rejected,iteration_finished") ||
+			!strings.Contains(joined, "approval_requested,story_approved,iteration_finished,story_passed") {
+			t.Errorf("Unexpected events: %v", events)
+		}
+		if strings.Count(joined, "story_passed") != 1 {
+			t.Errorf("Expected story_passed only once approved, got %v", events)
+		}
+	})
+
+	t.Run("per story", func(t *testing.T) {
+		calls := 0
+		run := func(dir string, config *Config) error {
+			runner := &Runner{
+				Config:  config,
+				WorkDir: dir,
+				Agent:   passingAgent(),
+				Approver: ApproverFunc(func(context.Context, ApprovalRequest) (Approval, error) {
+					calls++
+					return Approval{Approved: true}, nil
+				}),
+			}
+			_, err := runner.Run(context.Background())
+			return err
+		}
+
+		if err := run(setupRunnerRepo(t)); err != nil || calls != 0 {
+			t.Errorf("Expected no approval without the flag, got %d call(s), %v", calls, err)
+		}
+
+		dir, config := setupRunnerRepo(t)
+		SavePRD(filepath.Join(dir, ".ralph", "prd.yaml"), &PRD{BranchName: "ralph/feature", UserStories: []UserStory{{ID: "US-1", RequireApproval: true}}})
+		if err := run(dir, config); err != nil || calls != 1 {
+			t.Errorf("Expected approval for the flagged story, got %d call(s), %v", calls, err)
+		}
+	})
+
+	t.Run("no approver", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		config.RequireApproval = true
+		runner := &Runner{Config: config, WorkDir: dir, Agent: passingAgent()}
+
+		result, err := runner.Run(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "requires approval") || result.Reason != StopError {
+			t.Errorf("Expected an error without an approver, got %v, %+v", err, result)
+		}
+	})
+}
+
+func TestReviewNote(t *testing.T) {
+	if got := reviewNote("", 2, "  Add tests\n"); got != "Rejected in review after iteration 2: Add tests" {
+		t.Errorf("Unexpected note %q", got)
+	}
+	if got := reviewNote("Use the cache", 3, ""); got != "Use the cache\nRejected in review after iteration 3." {
+		t.Errorf("Unexpected note %q", got)
+	}
+}
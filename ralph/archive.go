@@ -0,0 +1,42 @@
+package ralph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Archive copies the PRD and progress log to
+// .ralph/archive/<date>-<branch> and returns the archive folder.
+func Archive(ralphDir, branch string) (string, error) {
+	date := time.Now().Format("2006-01-02")
+	folderName := strings.TrimPrefix(branch, ".ralph/")
+	archiveFolder := filepath.Join(ralphDir, "archive", date+"-"+folderName)
+
+	if err := os.MkdirAll(archiveFolder, 0755); err != nil {
+		return "", err
+	}
+	copyFile(filepath.Join(ralphDir, "prd.yaml"), filepath.Join(archiveFolder, "prd.yaml"))
+	copyFile(filepath.Join(ralphDir, "progress.txt"), filepath.Join(archiveFolder, "progress.txt"))
+
+	return archiveFolder, nil
+}
+
+// PendingArchive returns the branch of the previous run when the PRD has
+// moved to a different branch since, meaning the previous run should be
+// archived before starting.
+func PendingArchive(ralphDir string) string {
+	prdFile := filepath.Join(ralphDir, "prd.yaml")
+	lastBranchFile := filepath.Join(ralphDir, ".last-branch")
+	if !fileExists(prdFile) || !fileExists(lastBranchFile) {
+		return ""
+	}
+
+	currentBranch := getBranchFromPRD(prdFile)
+	lastBranch := readFile(lastBranchFile)
+	if currentBranch == "" || lastBranch == "" || currentBranch == lastBranch {
+		return ""
+	}
+	return lastBranch
+}
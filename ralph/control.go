@@ -0,0 +1,165 @@
+package ralph
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Control lets other goroutines intervene in a run. Requests take effect
+// between iterations, so the current iteration always finishes. The zero
+// value is ready to use.
+type Control struct {
+	mu      sync.Mutex
+	paused  bool
+	resumed chan struct{}
+	stop    bool
+	skipped map[string]bool
+}
+
+// ControlState is a snapshot of the requests made through a Control.
+type ControlState struct {
+	Paused             bool     `json:"paused"`
+	StopAfterIteration bool     `json:"stop_after_iteration"`
+	Skipped            []string `json:"skipped,omitempty"`
+}
+
+// Pause holds the run before its next iteration until Resume is called.
+func (c *Control) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		c.paused = true
+		c.resumed = make(chan struct{})
+	}
+}
+
+// Resume lets a paused run continue.
+func (c *Control) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resumeLocked()
+}
+
+func (c *Control) resumeLocked() {
+	if c.paused {
+		c.paused = false
+		close(c.resumed)
+	}
+}
+
+// Skip leaves the story out of the rest of the run.
+func (c *Control) Skip(storyID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.skipped == nil {
+		c.skipped = map[string]bool{}
+	}
+	c.skipped[storyID] = true
+}
+
+// StopAfterIteration ends the run once the current iteration finishes, also
+// when it is paused.
+func (c *Control) StopAfterIteration() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stop = true
+	c.resumeLocked()
+}
+
+// State returns the current requests.
+func (c *Control) State() ControlState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := ControlState{Paused: c.paused, StopAfterIteration: c.stop}
+	for id := range c.skipped {
+		state.Skipped = append(state.Skipped, id)
+	}
+	sort.Strings(state.Skipped)
+	return state
+}
+
+// waitResumed blocks while the run is paused.
+func (c *Control) waitResumed(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.paused {
+		c.mu.Unlock()
+		return nil
+	}
+	resumed := c.resumed
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resumed:
+		return nil
+	}
+}
+
+// checkpoint applies the control requests before an iteration. It returns
+// false when the run should stop.
+func (r *Runner) checkpoint(ctx context.Context, iteration int) (bool, error) {
+	for _, id := range r.Control.State().Skipped {
+		if !r.skipped[id] {
+			r.skipped[id] = true
+			r.emit(Event{Type: EventStorySkipped, Iteration: iteration, Stories: []string{id}, Message: fmt.Sprintf("Skipping story %s for the rest of the run", id)})
+		}
+	}
+
+	if r.Control.State().Paused {
+		r.emit(Event{Type: EventPaused, Iteration: iteration, Message: fmt.Sprintf("Paused before iteration %d", iteration)})
+		if err := r.Control.waitResumed(ctx); err != nil {
+			return false, err
+		}
+		if !r.Control.State().StopAfterIteration {
+			r.emit(Event{Type: EventResumed, Iteration: iteration, Message: "Resumed"})
+		}
+	}
+
+	if r.Control.State().StopAfterIteration {
+		r.stopped = true
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// eligibleStories is EligibleStories without the stories skipped in this run.
+func (r *Runner) eligibleStories(prd *PRD, n int) []UserStory {
+	filtered := *prd
+	filtered.UserStories = nil
+	for _, story := range prd.UserStories {
+		if !r.skipped[story.ID] || story.Passes {
+			filtered.UserStories = append(filtered.UserStories, story)
+		}
+	}
+	return EligibleStories(&filtered, n)
+}
+
+// onlySkippedLeft reports whether every story that doesn't pass yet was
+// skipped or waits on one that was.
+func (r *Runner) onlySkippedLeft(prd *PRD) bool {
+	return len(r.skipped) > 0 && !allStoriesPass(prd) && len(r.eligibleStories(prd, 1)) == 0
+}
+
+// skipPrompt tells a sequential agent to leave the skipped stories alone.
+func (r *Runner) skipPrompt(prompt []byte) []byte {
+	if len(r.skipped) == 0 {
+		return prompt
+	}
+	ids := make([]string, 0, len(r.skipped))
+	for id := range r.skipped {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var buf bytes.Buffer
+	buf.Write(prompt)
+	fmt.Fprintf(&buf, "\n\n## Skipped Stories\n\n")
+	fmt.Fprintf(&buf, "Do not work on these stories in this run, even if they have the highest priority: %s.\n", strings.Join(ids, ", "))
+	return buf.Bytes()
+}
@@ -0,0 +1,143 @@
+package ralph
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestControlState(t *testing.T) {
+	var c Control
+	c.Pause()
+	c.Skip("US-2")
+	c.Skip("US-1")
+	if state := c.State(); !state.Paused || strings.Join(state.Skipped, ",") != "US-1,US-2" {
+		t.Errorf("Unexpected state: %+v", state)
+	}
+
+	c.StopAfterIteration()
+	if state := c.State(); state.Paused || !state.StopAfterIteration {
+		t.Errorf("Expected stop to resume the run, got %+v", state)
+	}
+	if err := c.waitResumed(context.Background()); err != nil {
+		t.Errorf("Expected no wait once resumed, got %v", err)
+	}
+}
+
+func TestRunnerControl(t *testing.T) {
+	setup := func(t *testing.T) (string, *Config) {
+		dir, config := setupRunnerRepo(t)
+		prd := &PRD{BranchName: "ralph/feature", UserStories: []UserStory{{ID: "US-1", Priority: 1}, {ID: "US-2", Priority: 2}}}
+		SavePRD(filepath.Join(dir, ".ralph", "prd.yaml"), prd)
+		return dir, config
+	}
+
+	t.Run("pause and resume", func(t *testing.T) {
+		dir, config := setup(t)
+		control := &Control{}
+		calls := 0
+		var events []EventType
+		runner := &Runner{
+			Config:  config,
+			WorkDir: dir,
+			Control: control,
+			Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+				calls++
+				if calls == 1 {
+					control.Pause()
+					return "", nil
+				}
+				return CompleteSignal, nil
+			}),
+			OnEvent: func(e Event) {
+				events = append(events, e.Type)
+				if e.Type == EventPaused {
+					go control.Resume()
+				}
+			},
+		}
+
+		result, err := runner.Run(context.Background())
+		if err != nil || !result.Complete || len(result.Records) != 2 {
+			t.Fatalf("Expected completion after resuming, got %v, %+v", err, result)
+		}
+		if !strings.Contains(strings.Join(toStrings(events), ","), "iteration_finished,paused,resumed,iteration_started") {
+			t.Errorf("Expected pause between iterations, got %v", events)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		dir, config := setup(t)
+		control := &Control{}
+		var prompts []string
+		var started [][]string
+		runner := &Runner{
+			Config:  config,
+			WorkDir: dir,
+			Control: control,
+			Agent: agentFunc(func(_ context.Context, _ string, prompt []byte, _, _ io.Writer) (string, error) {
+				prompts = append(prompts, string(prompt))
+				if len(prompts) == 1 {
+					control.Skip("US-1")
+					return "", nil
+				}
+				return CompleteSignal, nil
+			}),
+			OnEvent: func(e Event) {
+				if e.Type == EventIterationStarted {
+					started = append(started, e.Stories)
+				}
+			},
+		}
+
+		if _, err := runner.Run(context.Background()); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if len(started) != 2 || started[0][0] != "US-1" || started[1][0] != "US-2" {
+			t.Errorf("Expected US-1 then US-2, got %v", started)
+		}
+		if strings.Contains(prompts[0], "Skipped Stories") || !strings.Contains(prompts[1], "even if they have the highest priority: US-1.") {
+			t.Errorf("Expected the skip in the second prompt only, got %q", prompts)
+		}
+	})
+
+	t.Run("stop after iteration", func(t *testing.T) {
+		dir, config := setup(t)
+		control := &Control{}
+		runner := &Runner{Config: config, WorkDir: dir, Control: control, Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+			control.StopAfterIteration()
+			return "", nil
+		})}
+
+		result, err := runner.Run(context.Background())
+		if err != nil || result.Reason != StopRequested || len(result.Records) != 1 {
+			t.Errorf("Expected stop after 1 iteration, got %v, %+v", err, result)
+		}
+	})
+
+	t.Run("every story skipped", func(t *testing.T) {
+		dir, config := setup(t)
+		control := &Control{}
+		control.Skip("US-1")
+		control.Skip("US-2")
+		runner := &Runner{Config: config, WorkDir: dir, Control: control, Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+			t.Error("Expected agent not to run")
+			return "", nil
+		})}
+
+		result, err := runner.Run(context.Background())
+		if err != nil || result.Reason != StopRequested || len(result.Records) != 0 {
+			t.Errorf("Expected stop before any iteration, got %v, %+v", err, result)
+		}
+	})
+}
+
+func toStrings(events []EventType) []string {
+	s := make([]string, len(events))
+	for i, e := range events {
+		s[i] = string(e)
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package ralph
+
+import "time"
+
+// EventType identifies what happened in an Event.
+type EventType string
+
+const (
+	EventRunStarted        EventType = "run_started"
+	EventArchived          EventType = "archived"
+	EventBranchCreated     EventType = "branch_created"
+	EventIterationStarted  EventType = "iteration_started"
+	EventIterationFinished EventType = "iteration_finished"
+	EventIterationSkipped  EventType = "iteration_skipped"
+	EventPaused            EventType = "paused"
+	EventResumed           EventType = "resumed"
+	EventRolledBack        EventType = "rolled_back"
+	EventStoryMerged       EventType = "story_merged"
+	EventStoryPassed       EventType = "story_passed"
+	EventApprovalRequested EventType = "approval_requested"
+	EventStoryApproved     EventType = "story_approved"
+	EventStoryRejected     EventType = "story_rejected"
+	EventStoryRetry        EventType = "story_retry"
+	EventStorySkipped      EventType = "story_skipped"
+	EventWarning           EventType = "warning"
+	EventRunFinished       EventType = "run_finished"
+)
+
+var eventTypes = []EventType{
+	EventRunStarted, EventArchived, EventBranchCreated,
+	EventIterationStarted, EventIterationFinished, EventIterationSkipped,
+	EventPaused, EventResumed, EventRolledBack, EventStoryMerged,
+	EventStoryPassed, EventApprovalRequested, EventStoryApproved,
+	EventStoryRejected, EventStoryRetry, EventStorySkipped, EventWarning,
+	EventRunFinished,
+}
+
+// Event reports progress of a run to Runner.OnEvent. Message is a human
+// readable description; the other fields are set where they apply.
+type Event struct {
+	Type      EventType        `json:"type"`
+	Time      time.Time        `json:"time"`
+	RunID     string           `json:"run_id,omitempty"`
+	Iteration int              `json:"iteration,omitempty"`
+	Stories   []string         `json:"stories,omitempty"`
+	Record    *IterationRecord `json:"record,omitempty"`
+	Result    *Result          `json:"result,omitempty"`
+	Message   string           `json:"message,omitempty"`
+}
@@ -0,0 +1,210 @@
+package ralph
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func runGit(dir string, args ...string) (string, error) {
+	return runGitEnv(dir, nil, args...)
+}
+
+// runGitEnv runs git with env added to the environment.
+func runGitEnv(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+func CurrentBranch(dir string) (string, error) {
+	return runGit(dir, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+func BranchExists(dir, branch string) bool {
+	_, err := runGit(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// DirtyFiles lists uncommitted changes in the working tree. Files under
+// .ralph/ are ignored since go-ralph and the agent keep their state there.
+func DirtyFiles(dir string) ([]string, error) {
+	out, err := runGit(dir, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		path := strings.Trim(line[3:], "\"")
+		if strings.HasPrefix(path, ".ralph/") {
+			continue
+		}
+		files = append(files, path)
+	}
+
+	return files, nil
+}
+
+// dirtyState maps the uncommitted changes in the working tree to the hash of
+// their content, empty for deleted files, so that changes made later can be
+// told apart from the ones already there.
+func dirtyState(dir string) (map[string]string, error) {
+	files, err := DirtyFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	state := make(map[string]string, len(files))
+	var existing []string
+	for _, file := range files {
+		state[file] = ""
+		if info, err := os.Stat(filepath.Join(dir, file)); err == nil && info.Mode().IsRegular() {
+			existing = append(existing, file)
+		}
+	}
+	if len(existing) == 0 {
+		return state, nil
+	}
+
+	out, err := runGit(dir, append([]string{"hash-object", "--"}, existing...)...)
+	if err != nil {
+		return nil, err
+	}
+	for i, hash := range strings.Split(out, "\n") {
+		if i < len(existing) {
+			state[existing[i]] = hash
+		}
+	}
+	return state, nil
+}
+
+// newDirtyFiles lists the uncommitted changes that are not in before, or
+// whose content changed since.
+func newDirtyFiles(dir string, before map[string]string) ([]string, error) {
+	after, err := dirtyState(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for file, hash := range after {
+		if previous, ok := before[file]; !ok || previous != hash {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ensureBranch checks out branch, creating it from baseBranch when it does
+// not exist yet. It returns true when a new branch was created.
+func ensureBranch(dir, branch, baseBranch string) (bool, error) {
+	current, err := CurrentBranch(dir)
+	if err != nil {
+		return false, err
+	}
+	if current == branch {
+		return false, nil
+	}
+
+	if BranchExists(dir, branch) {
+		_, err := runGit(dir, "checkout", branch)
+		return false, err
+	}
+
+	if !BranchExists(dir, baseBranch) {
+		return false, fmt.Errorf("base branch '%s' does not exist", baseBranch)
+	}
+
+	_, err = runGit(dir, "checkout", "-b", branch, baseBranch)
+	return err == nil, err
+}
+
+type GitCommit struct {
+	Hash    string `json:"hash"`
+	Subject string `json:"subject"`
+}
+
+type FileChange struct {
+	Path       string `json:"path"`
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+}
+
+// ShortHash abbreviates a commit hash to 7 characters for display.
+func ShortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+func gitHead(dir string) (string, error) {
+	return runGit(dir, "rev-parse", "HEAD")
+}
+
+// commitsBetween lists the commits reachable from to but not from, oldest first.
+func commitsBetween(dir, from, to string) ([]GitCommit, error) {
+	out, err := runGit(dir, "log", "--reverse", "--format=%H%x09%s", from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []GitCommit
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		hash, subject, _ := strings.Cut(line, "\t")
+		commits = append(commits, GitCommit{Hash: hash, Subject: subject})
+	}
+
+	return commits, nil
+}
+
+// diffStats returns insertions and deletions per file between two commits.
+// Binary files are reported with zero counts.
+func diffStats(dir, from, to string) ([]FileChange, error) {
+	out, err := runGit(dir, "diff", "--numstat", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		insertions, _ := strconv.Atoi(fields[0])
+		deletions, _ := strconv.Atoi(fields[1])
+		changes = append(changes, FileChange{Path: fields[2], Insertions: insertions, Deletions: deletions})
+	}
+
+	return changes, nil
+}
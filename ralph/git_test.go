@@ -0,0 +1,198 @@
+package ralph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+
+	t.Setenv("GIT_AUTHOR_NAME", "Ralph Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ralph@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Ralph Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "ralph@example.com")
+
+	dir := t.TempDir()
+	if _, err := runGit(dir, "init", "--initial-branch=main"); err != nil {
+		t.Fatalf("Failed to init repo: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("test\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if _, err := runGit(dir, "add", "-A"); err != nil {
+		t.Fatalf("Failed to stage files: %v", err)
+	}
+	if _, err := runGit(dir, "commit", "-m", "initial"); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	return dir
+}
+
+func TestCurrentGitBranch(t *testing.T) {
+	dir := initTestRepo(t)
+
+	branch, err := CurrentBranch(dir)
+	if err != nil {
+		t.Fatalf("CurrentBranch failed: %v", err)
+	}
+	if branch != "main" {
+		t.Errorf("Expected branch 'main', got '%s'", branch)
+	}
+}
+
+func TestDirtyFiles(t *testing.T) {
+	t.Run("clean tree", func(t *testing.T) {
+		dir := initTestRepo(t)
+
+		files, err := DirtyFiles(dir)
+		if err != nil {
+			t.Fatalf("DirtyFiles failed: %v", err)
+		}
+		if len(files) != 0 {
+			t.Errorf("Expected no dirty files, got %v", files)
+		}
+	})
+
+	t.Run("modified and untracked files", func(t *testing.T) {
+		dir := initTestRepo(t)
+		os.WriteFile(filepath.Join(dir, "README.md"), []byte("changed\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new\n"), 0644)
+
+		files, err := DirtyFiles(dir)
+		if err != nil {
+			t.Fatalf("DirtyFiles failed: %v", err)
+		}
+		if len(files) != 2 {
+			t.Fatalf("Expected 2 dirty files, got %v", files)
+		}
+		if files[0] != "README.md" {
+			t.Errorf("Expected 'README.md', got '%s'", files[0])
+		}
+	})
+
+	t.Run("ignores ralph directory", func(t *testing.T) {
+		dir := initTestRepo(t)
+		os.MkdirAll(filepath.Join(dir, ".ralph"), 0755)
+		os.WriteFile(filepath.Join(dir, ".ralph", "progress.txt"), []byte("log\n"), 0644)
+
+		files, err := DirtyFiles(dir)
+		if err != nil {
+			t.Fatalf("DirtyFiles failed: %v", err)
+		}
+		if len(files) != 0 {
+			t.Errorf("Expected .ralph files to be ignored, got %v", files)
+		}
+	})
+}
+
+func TestNewDirtyFiles(t *testing.T) {
+	dir := initTestRepo(t)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("user edit\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "todo.txt"), []byte("todo\n"), 0644)
+
+	before, err := dirtyState(dir)
+	if err != nil || len(before) != 3 {
+		t.Fatalf("Expected 3 dirty files, got %v, %v", before, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("agent edit\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "wip.go"), []byte("package wip\n"), 0644)
+	os.Remove(filepath.Join(dir, "todo.txt"))
+
+	files, err := newDirtyFiles(dir, before)
+	if err != nil {
+		t.Fatalf("newDirtyFiles failed: %v", err)
+	}
+	if strings.Join(files, ",") != "README.md,wip.go" {
+		t.Errorf("Expected README.md and wip.go, got %v", files)
+	}
+}
+
+func TestEnsureBranch(t *testing.T) {
+	t.Run("creates missing branch from base", func(t *testing.T) {
+		dir := initTestRepo(t)
+
+		created, err := ensureBranch(dir, "ralph/feature", "main")
+		if err != nil {
+			t.Fatalf("ensureBranch failed: %v", err)
+		}
+		if !created {
+			t.Error("Expected branch to be created")
+		}
+
+		branch, _ := CurrentBranch(dir)
+		if branch != "ralph/feature" {
+			t.Errorf("Expected branch 'ralph/feature', got '%s'", branch)
+		}
+	})
+
+	t.Run("checks out existing branch", func(t *testing.T) {
+		dir := initTestRepo(t)
+		runGit(dir, "branch", "ralph/feature")
+
+		created, err := ensureBranch(dir, "ralph/feature", "main")
+		if err != nil {
+			t.Fatalf("ensureBranch failed: %v", err)
+		}
+		if created {
+			t.Error("Expected existing branch to be reused")
+		}
+
+		branch, _ := CurrentBranch(dir)
+		if branch != "ralph/feature" {
+			t.Errorf("Expected branch 'ralph/feature', got '%s'", branch)
+		}
+	})
+
+	t.Run("missing base branch", func(t *testing.T) {
+		dir := initTestRepo(t)
+
+		_, err := ensureBranch(dir, "ralph/feature", "develop")
+		if err == nil {
+			t.Error("Expected error for missing base branch")
+		}
+	})
+}
+
+func TestCommitsAndDiffStats(t *testing.T) {
+	dir := initTestRepo(t)
+	before, err := gitHead(dir)
+	if err != nil {
+		t.Fatalf("gitHead failed: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("line one\nline two\n"), 0644)
+	runGit(dir, "commit", "-am", "update readme")
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	runGit(dir, "add", "-A")
+	runGit(dir, "commit", "-m", "add main")
+
+	after, _ := gitHead(dir)
+
+	commits, err := commitsBetween(dir, before, after)
+	if err != nil {
+		t.Fatalf("commitsBetween failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d", len(commits))
+	}
+	if commits[0].Subject != "update readme" || commits[1].Subject != "add main" {
+		t.Errorf("Unexpected commit order: %v", commits)
+	}
+
+	files, err := diffStats(dir, before, after)
+	if err != nil {
+		t.Fatalf("diffStats failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 changed files, got %d", len(files))
+	}
+	if files[0].Path != "README.md" || files[0].Insertions != 2 || files[0].Deletions != 1 {
+		t.Errorf("Unexpected stats for README.md: %+v", files[0])
+	}
+}
@@ -0,0 +1,163 @@
+package ralph
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Hook names, also the keys of the hooks: config section.
+const (
+	HookPreRun        = "pre_run"
+	HookPreIteration  = "pre_iteration"
+	HookPostIteration = "post_iteration"
+	HookOnComplete    = "on_complete"
+	HookOnFailure     = "on_failure"
+)
+
+// What a failing pre_iteration hook does to the iteration.
+const (
+	HookFailureAbort = "abort"
+	HookFailureSkip  = "skip"
+)
+
+// Hooks are shell commands run in the working directory at points of the run
+// lifecycle. Each hook runs its commands in order and stops at the first one
+// that fails.
+type Hooks struct {
+	// PreRun runs once before the first iteration. A failure aborts the run.
+	PreRun []string `yaml:"pre_run,omitempty"`
+	// PreIteration runs before every iteration, see PreIterationFailure.
+	PreIteration []string `yaml:"pre_iteration,omitempty"`
+	// PostIteration runs after every iteration. Failures are only reported.
+	PostIteration []string `yaml:"post_iteration,omitempty"`
+	// OnComplete runs when the agent completes every story.
+	OnComplete []string `yaml:"on_complete,omitempty"`
+	// OnFailure runs when the run ends without completing, unless it was
+	// interrupted or stopped on request.
+	OnFailure []string `yaml:"on_failure,omitempty"`
+	// PreIterationFailure is HookFailureAbort (the default) to stop the run
+	// when a pre_iteration hook fails, or HookFailureSkip to skip the
+	// iteration and carry on with the next one.
+	PreIterationFailure string `yaml:"pre_iteration_failure,omitempty"`
+}
+
+// Commands returns the commands configured for the named hook.
+func (h Hooks) Commands(name string) []string {
+	switch name {
+	case HookPreRun:
+		return h.PreRun
+	case HookPreIteration:
+		return h.PreIteration
+	case HookPostIteration:
+		return h.PostIteration
+	case HookOnComplete:
+		return h.OnComplete
+	case HookOnFailure:
+		return h.OnFailure
+	}
+	return nil
+}
+
+// hookEnv is the context passed to hook commands as RALPH_* environment
+// variables.
+type hookEnv struct {
+	Iteration int
+	Stories   []string
+	Record    *IterationRecord
+	Err       error
+}
+
+// runHook runs the commands of the named hook with sh -c, sending their
+// output to the runner's Stdout and Stderr.
+func (r *Runner) runHook(ctx context.Context, name string, env hookEnv) error {
+	commands := r.Config.Hooks.Commands(name)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	vars := []string{
+		"RALPH_HOOK=" + name,
+		"RALPH_RUN_ID=" + r.runID,
+		"RALPH_BRANCH=" + r.branch,
+	}
+	if env.Iteration > 0 {
+		vars = append(vars, "RALPH_ITERATION="+strconv.Itoa(env.Iteration))
+	}
+	if len(env.Stories) > 0 {
+		vars = append(vars, "RALPH_STORY_ID="+strings.Join(env.Stories, ","))
+	}
+	if env.Record != nil {
+		vars = append(vars, "RALPH_EXIT_CODE="+strconv.Itoa(env.Record.ExitCode))
+	}
+	if env.Err != nil {
+		vars = append(vars, "RALPH_ERROR="+env.Err.Error())
+	}
+
+	for _, command := range commands {
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = r.WorkDir
+		cmd.Env = append(os.Environ(), vars...)
+		cmd.Stdout = r.Stdout
+		cmd.Stderr = r.Stderr
+		span := r.startSpan("hook "+name, r.parentSpan(), map[string]any{"ralph.hook": name, "ralph.command": command})
+		err := cmd.Run()
+		span.Attributes["ralph.exit_code"] = exitCode(err)
+		r.endSpan(span, err)
+		if err != nil {
+			return fmt.Errorf("%s hook '%s' failed: %w", name, command, err)
+		}
+	}
+
+	return nil
+}
+
+// runFinalHook runs on_complete or on_failure once the run is over. Failures
+// are reported as warnings since the outcome of the run is already decided.
+func (r *Runner) runFinalHook(ctx context.Context, result Result, runErr error) {
+	env := hookEnv{Err: runErr}
+	if len(result.Records) > 0 {
+		last := result.Records[len(result.Records)-1]
+		env.Iteration = last.Iteration
+		env.Record = &last
+	}
+
+	name := HookOnFailure
+	switch {
+	case result.Reason == StopComplete:
+		name = HookOnComplete
+	case result.Reason == StopInterrupted || result.Reason == StopRequested:
+		return
+	}
+
+	if err := r.runHook(ctx, name, env); err != nil {
+		r.emit(Event{Type: EventWarning, Message: err.Error()})
+	}
+}
+
+// preIteration runs the pre_iteration hook. It returns false when the
+// iteration should be skipped.
+func (r *Runner) preIteration(ctx context.Context, iteration int, stories []string) (bool, error) {
+	err := r.runHook(ctx, HookPreIteration, hookEnv{Iteration: iteration, Stories: stories})
+	switch {
+	case err == nil:
+		return true, nil
+	case ctx.Err() != nil:
+		return false, ctx.Err()
+	case r.Config.Hooks.PreIterationFailure == HookFailureSkip:
+		r.emit(Event{Type: EventIterationSkipped, Iteration: iteration, Stories: stories, Message: fmt.Sprintf("Skipping iteration %d: %v", iteration, err)})
+		return false, nil
+	}
+	return false, err
+}
+
+// postIteration runs the post_iteration hook for a finished iteration.
+func (r *Runner) postIteration(ctx context.Context, record IterationRecord, stories []string) {
+	err := r.runHook(ctx, HookPostIteration, hookEnv{Iteration: record.Iteration, Stories: stories, Record: &record})
+	if err != nil && ctx.Err() == nil {
+		r.emit(Event{Type: EventWarning, Iteration: record.Iteration, Message: err.Error()})
+	}
+}
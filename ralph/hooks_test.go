@@ -0,0 +1,144 @@
+package ralph
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunnerHooks(t *testing.T) {
+	// Every hook appends its name and context to a log outside the checkout
+	logHook := func(log string) string {
+		return `echo "$RALPH_HOOK it=$RALPH_ITERATION story=$RALPH_STORY_ID exit=$RALPH_EXIT_CODE run=${RALPH_RUN_ID:+set}" >> ` + log
+	}
+	readLog := func(t *testing.T, log string) []string {
+		t.Helper()
+		data, err := os.ReadFile(log)
+		if err != nil {
+			t.Fatalf("Failed to read hook log: %v", err)
+		}
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+	completeOn := func(iteration int) Agent {
+		calls := 0
+		return agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+			calls++
+			if calls == iteration {
+				return CompleteSignal, nil
+			}
+			return "", nil
+		})
+	}
+
+	t.Run("lifecycle", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		log := filepath.Join(t.TempDir(), "hooks.log")
+		hook := []string{logHook(log)}
+		config.Hooks = Hooks{PreRun: hook, PreIteration: hook, PostIteration: hook, OnComplete: hook, OnFailure: hook}
+
+		runner := &Runner{Config: config, WorkDir: dir, Agent: completeOn(2)}
+		if _, err := runner.Run(context.Background()); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		expected := []string{
+			"pre_run it= story= exit= run=set",
+			"pre_iteration it=1 story=US-1 exit= run=set",
+			"post_iteration it=1 story=US-1 exit=0 run=set",
+			"pre_iteration it=2 story=US-1 exit= run=set",
+			"post_iteration it=2 story=US-1 exit=0 run=set",
+			"on_complete it=2 story= exit=0 run=set",
+		}
+		if got := readLog(t, log); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("Expected hooks:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+		}
+	})
+
+	t.Run("pre_iteration failure aborts", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		log := filepath.Join(t.TempDir(), "hooks.log")
+		config.Hooks = Hooks{PreIteration: []string{"exit 3"}, OnFailure: []string{`echo "$RALPH_ERROR" >> ` + log}}
+
+		runner := &Runner{Config: config, WorkDir: dir, Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+			t.Error("Expected agent not to run")
+			return "", nil
+		})}
+		result, err := runner.Run(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "pre_iteration hook 'exit 3' failed") || len(result.Records) != 0 {
+			t.Errorf("Expected pre_iteration error before any iteration, got %v, %+v", err, result)
+		}
+		if got := readLog(t, log); len(got) != 1 || !strings.Contains(got[0], "exit status 3") {
+			t.Errorf("Expected on_failure to receive the error, got %v", got)
+		}
+	})
+
+	t.Run("pre_iteration failure skips", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		config.Hooks = Hooks{PreIteration: []string{`test "$RALPH_ITERATION" != 1`}, PreIterationFailure: HookFailureSkip}
+
+		var skipped []int
+		runner := &Runner{Config: config, WorkDir: dir, Agent: completeOn(1), OnEvent: func(e Event) {
+			if e.Type == EventIterationSkipped {
+				skipped = append(skipped, e.Iteration)
+			}
+		}}
+		result, err := runner.Run(context.Background())
+		if err != nil || !result.Complete {
+			t.Fatalf("Expected run to complete, got %v, %+v", err, result)
+		}
+		if len(skipped) != 1 || skipped[0] != 1 || len(result.Records) != 1 || result.Records[0].Iteration != 2 {
+			t.Errorf("Expected iteration 1 skipped and 2 run, got skipped %v, records %+v", skipped, result.Records)
+		}
+	})
+
+	t.Run("pre_run failure aborts", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		config.Hooks = Hooks{PreRun: []string{"false"}}
+
+		runner := &Runner{Config: config, WorkDir: dir, Agent: completeOn(1)}
+		if _, err := runner.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "pre_run hook") {
+			t.Errorf("Expected pre_run error, got %v", err)
+		}
+	})
+
+	t.Run("post_iteration failure warns", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		config.Hooks = Hooks{PostIteration: []string{"false"}}
+
+		var warnings []string
+		runner := &Runner{Config: config, WorkDir: dir, Agent: completeOn(1), OnEvent: func(e Event) {
+			if e.Type == EventWarning {
+				warnings = append(warnings, e.Message)
+			}
+		}}
+		result, err := runner.Run(context.Background())
+		if err != nil || !result.Complete {
+			t.Errorf("Expected run to complete, got %v", err)
+		}
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "post_iteration hook 'false' failed") {
+			t.Errorf("Expected post_iteration warning, got %v", warnings)
+		}
+	})
+
+	t.Run("interrupted runs skip on_failure", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		log := filepath.Join(t.TempDir(), "hooks.log")
+		config.Hooks = Hooks{OnFailure: []string{logHook(log)}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		runner := &Runner{Config: config, WorkDir: dir, Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+			cancel()
+			return "", context.Canceled
+		})}
+		if _, err := runner.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected cancellation, got %v", err)
+		}
+		if fileExists(log) {
+			t.Error("Expected on_failure not to run")
+		}
+	})
+}
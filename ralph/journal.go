@@ -0,0 +1,123 @@
+package ralph
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// IterationRecord captures what an iteration changed in the repository.
+type IterationRecord struct {
+	Iteration   int           `json:"iteration"`
+	StartedAt   time.Time     `json:"started_at"`
+	Duration    time.Duration `json:"duration"`
+	ExitCode    int           `json:"exit_code"`
+	Stories     []string      `json:"stories,omitempty"`
+	Conflicts   []string      `json:"conflicts,omitempty"`
+	Rejected    []string      `json:"rejected,omitempty"`
+	HeadBefore  string        `json:"head_before,omitempty"`
+	HeadAfter   string        `json:"head_after,omitempty"`
+	Commits     []GitCommit   `json:"commits,omitempty"`
+	Files       []FileChange  `json:"files,omitempty"`
+	Uncommitted []string      `json:"uncommitted,omitempty"`
+	Usage       *Usage        `json:"usage,omitempty"`
+	RolledBack  bool          `json:"rolled_back,omitempty"`
+	Patch       string        `json:"patch,omitempty"`
+	Complete    bool          `json:"complete,omitempty"`
+}
+
+func (r IterationRecord) Insertions() int {
+	total := 0
+	for _, f := range r.Files {
+		total += f.Insertions
+	}
+	return total
+}
+
+func (r IterationRecord) Deletions() int {
+	total := 0
+	for _, f := range r.Files {
+		total += f.Deletions
+	}
+	return total
+}
+
+// Journal appends one JSON line per iteration to journal.jsonl inside the
+// run directory (.ralph/runs/<run id>).
+type Journal struct {
+	RunID   string
+	Dir     string
+	Records []IterationRecord
+}
+
+func newJournal(ralphDir string, startedAt time.Time) (*Journal, error) {
+	runID := startedAt.Format("20060102-150405")
+	dir := filepath.Join(ralphDir, "runs", runID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Journal{RunID: runID, Dir: dir}, nil
+}
+
+func (j *Journal) Append(record IterationRecord) error {
+	j.Records = append(j.Records, record)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(j.Dir, "journal.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func LoadJournal(path string) ([]IterationRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []IterationRecord
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var record IterationRecord
+		if err := decoder.Decode(&record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// captureIteration fills in the git accounting for an iteration that started
+// at headBefore. Changes listed in dirtyBefore were there before the iteration
+// and are not counted as left behind by it.
+func captureIteration(workDir string, record *IterationRecord, dirtyBefore map[string]string) {
+	headAfter, err := gitHead(workDir)
+	if err != nil {
+		return
+	}
+	record.HeadAfter = headAfter
+
+	if record.HeadBefore != "" && record.HeadBefore != headAfter {
+		if commits, err := commitsBetween(workDir, record.HeadBefore, headAfter); err == nil {
+			record.Commits = commits
+		}
+		if files, err := diffStats(workDir, record.HeadBefore, headAfter); err == nil {
+			record.Files = files
+		}
+	}
+
+	if files, err := newDirtyFiles(workDir, dirtyBefore); err == nil {
+		record.Uncommitted = files
+	}
+}
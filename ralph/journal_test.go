@@ -0,0 +1,70 @@
+package ralph
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	ralphDir := t.TempDir()
+
+	journal, err := newJournal(ralphDir, time.Date(2026, 1, 24, 10, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("newJournal failed: %v", err)
+	}
+	if journal.RunID != "20260124-103000" {
+		t.Errorf("Expected run ID '20260124-103000', got '%s'", journal.RunID)
+	}
+
+	records := []IterationRecord{
+		{Iteration: 1, Commits: []GitCommit{{Hash: "abc", Subject: "feat: US-001"}}, Files: []FileChange{{Path: "a.go", Insertions: 3, Deletions: 1}}},
+		{Iteration: 2, ExitCode: 1, Uncommitted: []string{"b.go"}},
+	}
+	for _, r := range records {
+		if err := journal.Append(r); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	loaded, err := LoadJournal(filepath.Join(ralphDir, "runs", journal.RunID, "journal.jsonl"))
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(loaded))
+	}
+	if loaded[0].Insertions() != 3 || loaded[0].Deletions() != 1 {
+		t.Errorf("Unexpected totals: +%d/-%d", loaded[0].Insertions(), loaded[0].Deletions())
+	}
+	if loaded[1].ExitCode != 1 || len(loaded[1].Uncommitted) != 1 {
+		t.Errorf("Unexpected second record: %+v", loaded[1])
+	}
+}
+
+func TestCaptureIteration(t *testing.T) {
+	dir := initTestRepo(t)
+	before, _ := gitHead(dir)
+
+	os.WriteFile(filepath.Join(dir, "feature.go"), []byte("package main\n"), 0644)
+	runGit(dir, "add", "-A")
+	runGit(dir, "commit", "-m", "feat: US-001 - Feature")
+	os.WriteFile(filepath.Join(dir, "leftover.txt"), []byte("wip\n"), 0644)
+
+	record := IterationRecord{Iteration: 1, HeadBefore: before}
+	captureIteration(dir, &record, nil)
+
+	if record.HeadAfter == "" || record.HeadAfter == before {
+		t.Errorf("Expected HEAD to move, got '%s'", record.HeadAfter)
+	}
+	if len(record.Commits) != 1 || record.Commits[0].Subject != "feat: US-001 - Feature" {
+		t.Errorf("Unexpected commits: %v", record.Commits)
+	}
+	if len(record.Files) != 1 || record.Files[0].Path != "feature.go" {
+		t.Errorf("Unexpected files: %v", record.Files)
+	}
+	if len(record.Uncommitted) != 1 || record.Uncommitted[0] != "leftover.txt" {
+		t.Errorf("Unexpected uncommitted files: %v", record.Uncommitted)
+	}
+}
@@ -0,0 +1,127 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RunLock is the content of .ralph/ralph.lock, held for the duration of a run
+// so two go-ralph instances never work in the same checkout.
+type RunLock struct {
+	PID       int       `yaml:"pid"`
+	Host      string    `yaml:"host"`
+	StartedAt time.Time `yaml:"started_at"`
+
+	path string
+}
+
+func (l *RunLock) String() string {
+	return fmt.Sprintf("PID %d on %s since %s", l.PID, l.Host, l.StartedAt.Format(time.RFC1123))
+}
+
+// AcquireLock creates the lock file at path, clearing it first when it was
+// left behind by a process that is no longer running on this host. The
+// cleared lock, if any, is returned as well.
+func AcquireLock(path string) (*RunLock, *RunLock, error) {
+	host, _ := os.Hostname()
+	lock := &RunLock{PID: os.Getpid(), Host: host, StartedAt: time.Now(), path: path}
+
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Write the lock next to its final path and link it into place, so other
+	// runs never see a lock file without its content
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stale *RunLock
+	for attempt := 0; attempt < 2; attempt++ {
+		err := os.Link(tmp.Name(), path)
+		if err == nil {
+			return lock, stale, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, nil, err
+		}
+
+		holder, err := ReadLock(path)
+		if err == nil && !holder.stale(host) {
+			return nil, nil, fmt.Errorf("another go-ralph run holds %s (%s)", path, holder)
+		}
+
+		stale = holder
+		if stale == nil {
+			stale = &RunLock{path: path}
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, nil, err
+		}
+	}
+
+	return nil, nil, fmt.Errorf("failed to acquire %s", path)
+}
+
+func ReadLock(path string) (*RunLock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lock RunLock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+	if lock.PID == 0 {
+		return nil, fmt.Errorf("invalid lock file %s", path)
+	}
+	lock.path = path
+
+	return &lock, nil
+}
+
+// stale reports whether the lock holder is known to be gone. Locks held from
+// other hosts are never considered stale since their process can't be checked.
+func (l *RunLock) stale(host string) bool {
+	return l.Host == host && !processAlive(l.PID)
+}
+
+func (l *RunLock) Release() {
+	if l == nil {
+		return
+	}
+	if holder, err := ReadLock(l.path); err == nil && holder.PID == l.PID {
+		os.Remove(l.path)
+	}
+}
+
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,120 @@
+package ralph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func writeTestLock(t *testing.T, path string, lock RunLock) {
+	t.Helper()
+	data, err := yaml.Marshal(&lock)
+	if err != nil {
+		t.Fatalf("Failed to marshal lock: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write lock: %v", err)
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	host, _ := os.Hostname()
+
+	t.Run("acquire and release", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ralph.lock")
+
+		lock, stale, err := AcquireLock(path)
+		if err != nil {
+			t.Fatalf("AcquireLock failed: %v", err)
+		}
+		if stale != nil {
+			t.Errorf("Expected no stale lock, got %s", stale)
+		}
+		holder, err := ReadLock(path)
+		if err != nil {
+			t.Fatalf("ReadLock failed: %v", err)
+		}
+		if holder.PID != os.Getpid() || holder.Host != host {
+			t.Errorf("Unexpected lock holder: %s", holder)
+		}
+
+		lock.Release()
+		if fileExists(path) {
+			t.Error("Expected lock file to be removed")
+		}
+	})
+
+	t.Run("refuses when held by a live process", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ralph.lock")
+		writeTestLock(t, path, RunLock{PID: os.Getpid(), Host: host, StartedAt: time.Now()})
+
+		_, _, err := AcquireLock(path)
+		if err == nil {
+			t.Fatal("Expected error when lock is held")
+		}
+		if !strings.Contains(err.Error(), "PID") || !strings.Contains(err.Error(), host) {
+			t.Errorf("Expected error to name the holder, got: %v", err)
+		}
+	})
+
+	t.Run("refuses when held from another host", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ralph.lock")
+		writeTestLock(t, path, RunLock{PID: 999999999, Host: "other-host", StartedAt: time.Now()})
+
+		if _, _, err := AcquireLock(path); err == nil {
+			t.Error("Expected error when lock is held from another host")
+		}
+	})
+
+	t.Run("clears stale lock", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ralph.lock")
+		writeTestLock(t, path, RunLock{PID: 999999999, Host: host, StartedAt: time.Now()})
+
+		lock, stale, err := AcquireLock(path)
+		if err != nil {
+			t.Fatalf("Expected stale lock to be cleared: %v", err)
+		}
+		defer lock.Release()
+		if stale == nil || stale.PID != 999999999 {
+			t.Errorf("Expected the stale lock to be returned, got %v", stale)
+		}
+
+		holder, _ := ReadLock(path)
+		if holder.PID != os.Getpid() {
+			t.Errorf("Expected lock to be taken over, got %s", holder)
+		}
+	})
+
+	t.Run("concurrent starts", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "ralph.lock")
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		acquired := 0
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, _, err := AcquireLock(path); err == nil {
+					mu.Lock()
+					acquired++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if acquired != 1 {
+			t.Errorf("Expected exactly one run to get the lock, got %d", acquired)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("Expected only the lock file to be left, got %v", entries)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package ralph
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Notifications configures how the end of a run is announced.
+type Notifications struct {
+	// Bell rings the terminal bell.
+	Bell bool `yaml:"bell,omitempty"`
+	// OSC9 sends an OSC 9 escape sequence, which terminals such as iTerm2,
+	// Windows Terminal and kitty show as a desktop notification.
+	OSC9 bool `yaml:"osc9,omitempty"`
+	// Desktop shows a desktop notification with notify-send on Linux or
+	// osascript on macOS.
+	Desktop bool `yaml:"desktop,omitempty"`
+	// Webhooks receive run events as they happen.
+	Webhooks []Webhook `yaml:"webhooks,omitempty"`
+}
+
+// Notifiers returns the notifiers enabled in n. Terminal notifiers write to
+// terminal.
+func (n Notifications) Notifiers(terminal io.Writer) []Notifier {
+	var notifiers []Notifier
+	if n.Bell {
+		notifiers = append(notifiers, BellNotifier{W: terminal})
+	}
+	if n.OSC9 {
+		notifiers = append(notifiers, OSC9Notifier{W: terminal})
+	}
+	if n.Desktop {
+		notifiers = append(notifiers, DesktopNotifier{})
+	}
+	return notifiers
+}
+
+// Notification describes a finished run.
+type Notification struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Branch  string `json:"branch,omitempty"`
+	Result  Result `json:"result"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Notifier announces the end of a run.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// BellNotifier rings the terminal bell.
+type BellNotifier struct {
+	W io.Writer
+}
+
+func (b BellNotifier) Notify(ctx context.Context, n Notification) error {
+	_, err := io.WriteString(b.W, "\a")
+	return err
+}
+
+// OSC9Notifier sends the notification as an OSC 9 escape sequence.
+type OSC9Notifier struct {
+	W io.Writer
+}
+
+func (o OSC9Notifier) Notify(ctx context.Context, n Notification) error {
+	_, err := fmt.Fprintf(o.W, "\x1b]9;%s: %s\x07", n.Title, n.Message)
+	return err
+}
+
+// DesktopNotifier shows a desktop notification with notify-send, or osascript
+// on macOS.
+type DesktopNotifier struct{}
+
+func (DesktopNotifier) Notify(ctx context.Context, n Notification) error {
+	name, args := desktopCommand(runtime.GOOS, n)
+	if out, err := exec.CommandContext(ctx, name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", name, err, out)
+	}
+	return nil
+}
+
+func desktopCommand(goos string, n Notification) (string, []string) {
+	if goos == "darwin" {
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(n.Message), strconv.Quote(n.Title))
+		return "osascript", []string{"-e", script}
+	}
+	return "notify-send", []string{"--app-name=go-ralph", n.Title, n.Message}
+}
+
+// newNotification builds the notification for a finished run.
+func newNotification(branch string, result Result, err error) Notification {
+	n := Notification{Branch: branch, Result: result}
+
+	switch result.Reason {
+	case StopComplete:
+		n.Title = "Ralph completed all tasks"
+	case StopMaxIterations:
+		n.Title = "Ralph reached max iterations"
+	case StopRequested:
+		n.Title = "Ralph stopped as requested"
+	default:
+		n.Title = "Ralph stopped on an error"
+	}
+
+	n.Message = fmt.Sprintf("%d/%d stories pass after %d iteration(s) in %s",
+		result.StoriesPassed, result.StoriesTotal, len(result.Records), result.Duration.Round(time.Second))
+	if branch != "" {
+		n.Message = branch + ": " + n.Message
+	}
+	if err != nil {
+		n.Error = err.Error()
+		n.Message += ": " + n.Error
+	}
+
+	return n
+}
+
+// notify sends the notification for a finished run to every notifier.
+// Interrupted runs aren't announced, someone is at the terminal.
+func (r *Runner) notify(ctx context.Context, notification Notification) {
+	if notification.Result.Reason == StopInterrupted {
+		return
+	}
+
+	for _, notifier := range r.Notifiers {
+		if err := notifier.Notify(ctx, notification); err != nil {
+			r.emit(Event{Type: EventWarning, Message: fmt.Sprintf("failed to send notification: %v", err)})
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package ralph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// notifierFunc adapts a function to the Notifier interface.
+type notifierFunc func(ctx context.Context, n Notification) error
+
+func (f notifierFunc) Notify(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
+
+func TestNewNotification(t *testing.T) {
+	result := Result{
+		Reason:        StopMaxIterations,
+		Duration:      83*time.Minute + 400*time.Millisecond,
+		StoriesPassed: 3,
+		StoriesTotal:  5,
+		Records:       make([]IterationRecord, 10),
+	}
+
+	n := newNotification("ralph/feature", result, nil)
+	if n.Title != "Ralph reached max iterations" {
+		t.Errorf("Unexpected title: %s", n.Title)
+	}
+	if n.Message != "ralph/feature: 3/5 stories pass after 10 iteration(s) in 1h23m0s" {
+		t.Errorf("Unexpected message: %s", n.Message)
+	}
+
+	result.Reason = StopError
+	n = newNotification("", result, errors.New("boom"))
+	if n.Title != "Ralph stopped on an error" || !strings.HasSuffix(n.Message, ": boom") || n.Error != "boom" {
+		t.Errorf("Unexpected error notification: %+v", n)
+	}
+}
+
+func TestTerminalNotifiers(t *testing.T) {
+	n := Notification{Title: "Ralph completed all tasks", Message: "2/2 stories pass"}
+
+	var out bytes.Buffer
+	notifiers := Notifications{Bell: true, OSC9: true}.Notifiers(&out)
+	if len(notifiers) != 2 {
+		t.Fatalf("Expected 2 notifiers, got %d", len(notifiers))
+	}
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(context.Background(), n); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+	}
+
+	if out.String() != "\a\x1b]9;Ralph completed all tasks: 2/2 stories pass\x07" {
+		t.Errorf("Unexpected terminal output: %q", out.String())
+	}
+}
+
+func TestDesktopCommand(t *testing.T) {
+	n := Notification{Title: "Ralph completed all tasks", Message: `ralph/"x": done`}
+
+	name, args := desktopCommand("linux", n)
+	if name != "notify-send" || strings.Join(args, "|") != `--app-name=go-ralph|Ralph completed all tasks|ralph/"x": done` {
+		t.Errorf("Unexpected linux command: %s %v", name, args)
+	}
+
+	name, args = desktopCommand("darwin", n)
+	if name != "osascript" || args[1] != `display notification "ralph/\"x\": done" with title "Ralph completed all tasks"` {
+		t.Errorf("Unexpected macOS command: %s %v", name, args)
+	}
+}
+
+func TestRunnerNotify(t *testing.T) {
+	t.Run("complete", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+
+		var sent []Notification
+		runner := &Runner{
+			Config:  config,
+			WorkDir: dir,
+			Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+				return CompleteSignal, nil
+			}),
+			Notifiers: []Notifier{notifierFunc(func(ctx context.Context, n Notification) error {
+				sent = append(sent, n)
+				return errors.New("unreachable")
+			})},
+		}
+
+		var warnings []string
+		runner.OnEvent = func(e Event) {
+			if e.Type == EventWarning {
+				warnings = append(warnings, e.Message)
+			}
+		}
+
+		result, err := runner.Run(context.Background())
+		if err != nil || result.Reason != StopComplete || result.StoriesTotal != 1 {
+			t.Fatalf("Expected a complete run, got %v, %+v", err, result)
+		}
+		if len(sent) != 1 || sent[0].Title != "Ralph completed all tasks" || sent[0].Branch != "ralph/feature" {
+			t.Errorf("Expected completion notification, got %+v", sent)
+		}
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "failed to send notification: unreachable") {
+			t.Errorf("Expected notifier error as warning, got %v", warnings)
+		}
+	})
+
+	t.Run("max iterations", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		config.MaxIterations = 1
+
+		var sent []Notification
+		runner := &Runner{
+			Config:  config,
+			WorkDir: dir,
+			Agent:   CommandAgent{Command: "true"},
+			Notifiers: []Notifier{notifierFunc(func(ctx context.Context, n Notification) error {
+				sent = append(sent, n)
+				return nil
+			})},
+		}
+
+		result, err := runner.Run(context.Background())
+		if err != nil || result.Reason != StopMaxIterations {
+			t.Fatalf("Expected max iterations, got %v, %+v", err, result)
+		}
+		if len(sent) != 1 || !strings.Contains(sent[0].Message, "0/1 stories pass after 1 iteration(s)") {
+			t.Errorf("Expected max iterations notification, got %+v", sent)
+		}
+	})
+
+	t.Run("interrupted", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		runner := &Runner{
+			Config:  config,
+			WorkDir: dir,
+			Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+				cancel()
+				return "", context.Canceled
+			}),
+			Notifiers: []Notifier{notifierFunc(func(ctx context.Context, n Notification) error {
+				t.Error("Expected no notification for an interrupted run")
+				return nil
+			})},
+		}
+
+		if result, _ := runner.Run(ctx); result.Reason != StopInterrupted {
+			t.Errorf("Expected interrupted run, got %+v", result)
+		}
+	})
+}
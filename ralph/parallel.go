@@ -0,0 +1,472 @@
+package ralph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// storyRun is the outcome of running the agent on a single story in its own
+// git worktree.
+type storyRun struct {
+	Story    UserStory
+	Branch   string
+	Path     string
+	Base     string
+	ExitCode int
+	Passed   bool
+	Progress string
+	Err      error
+	AgentErr error
+	Usage    *Usage
+	Span     *Span
+}
+
+// EligibleStories returns up to n stories that do not pass yet and whose
+// dependencies all pass, highest priority first. Stories in the result never
+// depend on each other, so they can be worked on independently.
+func EligibleStories(prd *PRD, n int) []UserStory {
+	passed := make(map[string]bool)
+	for _, story := range prd.UserStories {
+		if story.Passes {
+			passed[story.ID] = true
+		}
+	}
+
+	var eligible []UserStory
+	for _, story := range prd.UserStories {
+		if story.Passes {
+			continue
+		}
+		ready := true
+		for _, dep := range story.DependsOn {
+			if !passed[dep] {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			eligible = append(eligible, story)
+		}
+	}
+
+	sort.SliceStable(eligible, func(i, j int) bool {
+		return eligible[i].Priority < eligible[j].Priority
+	})
+	if len(eligible) > n {
+		eligible = eligible[:n]
+	}
+
+	return eligible
+}
+
+func allStoriesPass(prd *PRD) bool {
+	for _, story := range prd.UserStories {
+		if !story.Passes {
+			return false
+		}
+	}
+	return true
+}
+
+func storyBranchName(branchName, storyID string) string {
+	return branchName + "-" + strings.ReplaceAll(storyID, " ", "-")
+}
+
+// StoryPrompt appends to prompt the instructions that pin a parallel agent to
+// a single story.
+func StoryPrompt(prompt []byte, story UserStory) []byte {
+	var buf bytes.Buffer
+	buf.Write(prompt)
+	fmt.Fprintf(&buf, "\n\n## Assigned Story\n\n")
+	fmt.Fprintf(&buf, "Other agents are working on other stories in parallel. Work ONLY on story %s (%s), ", story.ID, story.Title)
+	fmt.Fprintf(&buf, "ignoring the priority rule above, and do not touch any other story in the PRD.\n")
+	return buf.Bytes()
+}
+
+// prepareStoryWorktree creates a worktree on a per-story branch off
+// branchName and copies the ralph files into it. Worktrees are created one at
+// a time since git locks the repository while adding them.
+func prepareStoryWorktree(workDir, ralphDir string, config *Config, branchName string, story UserStory) storyRun {
+	run := storyRun{
+		Story:  story,
+		Branch: storyBranchName(branchName, story.ID),
+		Path:   filepath.Join(ralphDir, "worktrees", story.ID),
+	}
+
+	removeStoryWorktree(workDir, run)
+	if _, err := runGit(workDir, "worktree", "add", "-b", run.Branch, run.Path, branchName); err != nil {
+		run.Err = err
+		return run
+	}
+	run.Base, run.Err = gitHead(run.Path)
+	if run.Err != nil {
+		return run
+	}
+
+	worktreeRalphDir := filepath.Join(run.Path, ".ralph")
+	if err := os.MkdirAll(worktreeRalphDir, 0755); err != nil {
+		run.Err = err
+		return run
+	}
+	for _, name := range []string{"prd.yaml", "progress.txt", config.ToolPromptFile()} {
+		copyFile(filepath.Join(ralphDir, name), filepath.Join(worktreeRalphDir, name))
+	}
+
+	return run
+}
+
+// runStoryAgent runs the agent in the story's worktree and reads back whether
+// it marked the story as passing.
+func (r *Runner) runStoryAgent(ctx context.Context, run *storyRun, out *sync.Mutex) {
+	prompt, err := os.ReadFile(filepath.Join(r.RalphDir, r.Config.ToolPromptFile()))
+	if err != nil {
+		run.Err = err
+		return
+	}
+
+	// The span ends after all agents finish, so OnSpan isn't called concurrently
+	run.Span = r.startSpan("tool "+r.Config.Tool, r.iterationSpan, map[string]any{"ralph.tool": r.Config.Tool, "ralph.story_id": run.Story.ID})
+	prefix := "[" + run.Story.ID + "] "
+	stdout := &prefixWriter{mu: out, out: r.Stdout, prefix: prefix}
+	stderr := &prefixWriter{mu: out, out: r.Stderr, prefix: prefix}
+	output, err := r.Agent.Run(ctx, run.Path, StoryPrompt(prompt, run.Story), stdout, stderr)
+	stdout.Flush()
+	stderr.Flush()
+	run.ExitCode = exitCode(err)
+	run.AgentErr = err
+	if usage, ok := ParseUsage(output); ok {
+		run.Usage = &usage
+	}
+	run.Span.End = time.Now()
+	run.Span.Attributes["ralph.exit_code"] = run.ExitCode
+	setUsageAttributes(run.Span.Attributes, run.Usage)
+
+	worktreeRalphDir := filepath.Join(run.Path, ".ralph")
+	if prd, err := LoadPRD(filepath.Join(worktreeRalphDir, "prd.yaml")); err == nil {
+		for _, s := range prd.UserStories {
+			if s.ID == run.Story.ID {
+				run.Passed = s.Passes
+			}
+		}
+	}
+	run.Progress = readFile(filepath.Join(worktreeRalphDir, "progress.txt"))
+	if run.Passed {
+		run.Err = dropStoryState(*run)
+	}
+}
+
+// dropStoryState reverts what the story branch committed under .ralph/, so
+// merging it only brings in the story's code. Every agent appends to the
+// progress log and updates the PRD, which would conflict between stories or
+// clash with untracked copies in the main checkout. go-ralph carries the
+// passes flag and progress over itself.
+func dropStoryState(run storyRun) error {
+	changed, err := runGit(run.Path, "diff", "--name-only", "--no-renames", run.Base, "HEAD", "--", ".ralph")
+	if err != nil || changed == "" {
+		return err
+	}
+
+	for _, file := range strings.Split(changed, "\n") {
+		if _, err := runGit(run.Path, "cat-file", "-e", run.Base+":"+file); err == nil {
+			_, err = runGit(run.Path, "checkout", run.Base, "--", file)
+			if err != nil {
+				return err
+			}
+		} else if _, err := runGit(run.Path, "rm", "--cached", "--force", "--quiet", "--", file); err != nil {
+			return err
+		}
+	}
+
+	_, err = runGit(run.Path, "commit", "--quiet", "-m", fmt.Sprintf("Leave .ralph changes of story %s to go-ralph", run.Story.ID))
+	return err
+}
+
+func removeStoryWorktree(workDir string, run storyRun) {
+	if fileExists(run.Path) {
+		runGit(workDir, "worktree", "remove", "--force", run.Path)
+	}
+	if BranchExists(workDir, run.Branch) {
+		runGit(workDir, "branch", "-D", run.Branch)
+	}
+}
+
+// conflictError is returned by mergeStoryBranch when the story branch
+// conflicts with what was merged before it.
+type conflictError struct {
+	Files []string
+}
+
+func (e *conflictError) Error() string {
+	return "merge conflicts in " + strings.Join(e.Files, ", ")
+}
+
+// mergeStoryBranch merges a story branch into the current branch without
+// committing, so the story's progress and passes flag can go in the same
+// commit. A failed merge is aborted, and reported as a *conflictError when
+// files conflict.
+func mergeStoryBranch(workDir string, run storyRun) error {
+	if _, err := runGit(workDir, "merge", "--no-ff", "--no-commit", run.Branch); err != nil {
+		conflicts, _ := runGit(workDir, "diff", "--name-only", "--diff-filter=U")
+		runGit(workDir, "merge", "--abort")
+		if conflicts != "" {
+			return &conflictError{Files: strings.Split(conflicts, "\n")}
+		}
+		return err
+	}
+	return nil
+}
+
+// commitStoryMerge commits the merge started by mergeStoryBranch along with
+// the given ralph files, when git tracks them.
+func commitStoryMerge(workDir string, run storyRun, files ...string) error {
+	for _, file := range files {
+		if !gitTracked(workDir, file) {
+			continue
+		}
+		if _, err := runGit(workDir, "add", "--", file); err != nil {
+			return err
+		}
+	}
+
+	// A story branch without commits leaves no merge in progress
+	_, noMerge := runGit(workDir, "rev-parse", "--quiet", "--verify", "MERGE_HEAD")
+	if _, clean := runGit(workDir, "diff", "--cached", "--quiet"); noMerge != nil && clean == nil {
+		return nil
+	}
+
+	message := fmt.Sprintf("Merge story %s - %s", run.Story.ID, run.Story.Title)
+	_, err := runGit(workDir, "commit", "--quiet", "-m", message)
+	return err
+}
+
+func gitTracked(dir, path string) bool {
+	_, err := runGit(dir, "ls-files", "--error-unmatch", path)
+	return err == nil
+}
+
+// runParallel runs up to Parallel agents at a time, one per eligible story,
+// and merges the successful story branches back into branchName serially.
+// Each round counts as one iteration. It returns true when all stories pass.
+func (r *Runner) runParallel(ctx context.Context, journal *Journal, branchName string) (bool, error) {
+	prdFile := filepath.Join(r.RalphDir, "prd.yaml")
+	progressFile := filepath.Join(r.RalphDir, "progress.txt")
+
+	for i := 1; i <= r.Config.MaxIterations; i++ {
+		if ok, err := r.checkpoint(ctx, i); !ok {
+			return false, err
+		}
+
+		prd, err := LoadPRD(prdFile)
+		if err != nil {
+			return false, fmt.Errorf("loading PRD: %w", err)
+		}
+		if allStoriesPass(prd) {
+			return true, nil
+		}
+		if r.onlySkippedLeft(prd) {
+			r.stopped = true
+			r.emit(Event{Type: EventWarning, Iteration: i, Message: "every story left is skipped or depends on a skipped story"})
+			return false, nil
+		}
+
+		stories := r.eligibleStories(prd, r.Parallel)
+		if len(stories) == 0 {
+			return false, errors.New("no eligible stories left, check dependsOn for missing or circular dependencies")
+		}
+
+		ids := make([]string, len(stories))
+		for j, story := range stories {
+			ids[j] = story.ID
+		}
+		r.startIterationSpan(i, ids)
+		if ok, err := r.preIteration(ctx, i, ids); err != nil {
+			return false, err
+		} else if !ok {
+			r.iterationSpan.Attributes["ralph.skipped"] = true
+			r.endIterationSpan(nil)
+			continue
+		}
+		r.emit(Event{Type: EventIterationStarted, Iteration: i, Stories: ids})
+
+		record := IterationRecord{Iteration: i, StartedAt: time.Now(), Stories: ids}
+		record.HeadBefore, _ = gitHead(r.WorkDir)
+		dirtyBefore, _ := dirtyState(r.WorkDir)
+
+		runs := make([]storyRun, len(stories))
+		for j, story := range stories {
+			runs[j] = prepareStoryWorktree(r.WorkDir, r.RalphDir, r.Config, branchName, story)
+		}
+
+		var out sync.Mutex
+		var wg sync.WaitGroup
+		for j := range runs {
+			if runs[j].Err != nil {
+				continue
+			}
+			wg.Add(1)
+			go func(run *storyRun) {
+				defer wg.Done()
+				r.runStoryAgent(ctx, run, &out)
+			}(&runs[j])
+		}
+		wg.Wait()
+		for _, run := range runs {
+			if run.Span != nil {
+				r.endSpan(run.Span, run.AgentErr)
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			for _, run := range runs {
+				removeStoryWorktree(r.WorkDir, run)
+			}
+			return false, err
+		}
+
+		// Merge successful stories back one at a time
+		originalProgress := readFile(progressFile)
+		var stopErr error
+		for _, run := range runs {
+			if run.ExitCode != 0 && record.ExitCode == 0 {
+				record.ExitCode = run.ExitCode
+			}
+			if run.Usage != nil {
+				if record.Usage == nil {
+					record.Usage = &Usage{}
+				}
+				record.Usage.Add(*run.Usage)
+			}
+
+			switch {
+			case stopErr != nil:
+				// Stop merging once a merge or review fails, e.g. on Ctrl+C
+			case run.Err != nil:
+				r.emit(Event{Type: EventWarning, Iteration: i, Stories: []string{run.Story.ID}, Message: fmt.Sprintf("story %s failed to run: %v", run.Story.ID, run.Err)})
+			case !run.Passed:
+				r.emit(Event{Type: EventStoryRetry, Iteration: i, Stories: []string{run.Story.ID}, Message: fmt.Sprintf("Story %s did not pass, will retry", run.Story.ID)})
+			default:
+				passing := passingStories(prdFile)
+				head, _ := gitHead(r.WorkDir)
+				err := mergeStoryBranch(r.WorkDir, run)
+				var conflict *conflictError
+				if errors.As(err, &conflict) {
+					r.emit(Event{Type: EventStoryRetry, Iteration: i, Stories: []string{run.Story.ID}, Message: fmt.Sprintf("Story %s conflicts with %s, will retry: %v", run.Story.ID, branchName, err)})
+					record.Conflicts = append(record.Conflicts, run.Story.ID)
+					break
+				}
+				if err == nil {
+					if err := markStoryPassed(prdFile, run.Story.ID); err != nil {
+						r.emit(Event{Type: EventWarning, Iteration: i, Message: fmt.Sprintf("failed to update PRD: %v", err)})
+					}
+					appendStoryProgress(progressFile, originalProgress, run.Progress)
+					err = commitStoryMerge(r.WorkDir, run, prdFile, progressFile)
+				}
+				if err != nil {
+					stopErr = fmt.Errorf("merging story %s: %w", run.Story.ID, err)
+					break
+				}
+				r.emit(Event{Type: EventStoryMerged, Iteration: i, Stories: []string{run.Story.ID}, Message: fmt.Sprintf("Merged story %s into %s", run.Story.ID, branchName)})
+				rejected, err := r.reviewStories(ctx, i, prdFile, passing, head, "HEAD")
+				record.Rejected = append(record.Rejected, rejected...)
+				stopErr = err
+				r.emitPassedStories(i, prdFile, passing)
+			}
+
+			removeStoryWorktree(r.WorkDir, run)
+		}
+
+		record.Duration = time.Since(record.StartedAt)
+		captureIteration(r.WorkDir, &record, dirtyBefore)
+		r.appendRecord(journal, record)
+		if stopErr != nil {
+			return false, stopErr
+		}
+		r.postIteration(ctx, record, ids)
+		r.endIterationSpan(nil)
+	}
+
+	prd, err := LoadPRD(prdFile)
+	if err != nil {
+		return false, fmt.Errorf("loading PRD: %w", err)
+	}
+	return allStoriesPass(prd), nil
+}
+
+// markStoryPassed sets passes on a merged story unless the merge already
+// brought in the agent's PRD update.
+func markStoryPassed(prdFile, storyID string) error {
+	prd, err := LoadPRD(prdFile)
+	if err != nil {
+		return err
+	}
+	for _, story := range prd.UserStories {
+		if story.ID == storyID && story.Passes {
+			return nil
+		}
+	}
+	return SetStoryPasses(prdFile, storyID, true)
+}
+
+// appendStoryProgress copies what an agent appended to its worktree's
+// progress.txt into the main one.
+func appendStoryProgress(progressFile, original, storyProgress string) {
+	if !strings.HasPrefix(storyProgress, original) {
+		return
+	}
+	added := strings.TrimPrefix(storyProgress, original)
+	if strings.TrimSpace(added) == "" {
+		return
+	}
+
+	f, err := os.OpenFile(progressFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(added + "\n")
+}
+
+// prefixWriter writes complete lines to out with a prefix, so output of
+// agents running side by side stays readable.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		fmt.Fprintf(w.out, "%s%s", w.prefix, w.buf[:i+1])
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+func (w *prefixWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		fmt.Fprintf(w.out, "%s%s\n", w.prefix, w.buf)
+		w.buf = nil
+	}
+}
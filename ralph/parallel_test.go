@@ -0,0 +1,202 @@
+package ralph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEligibleStories(t *testing.T) {
+	prd := &PRD{
+		UserStories: []UserStory{
+			{ID: "US-1", Priority: 2, Passes: true},
+			{ID: "US-2", Priority: 3},
+			{ID: "US-3", Priority: 1, DependsOn: []string{"US-1"}},
+			{ID: "US-4", Priority: 1, DependsOn: []string{"US-2"}},
+			{ID: "US-5", Priority: 4},
+		},
+	}
+
+	stories := EligibleStories(prd, 2)
+	if len(stories) != 2 {
+		t.Fatalf("Expected 2 stories, got %d", len(stories))
+	}
+	if stories[0].ID != "US-3" || stories[1].ID != "US-2" {
+		t.Errorf("Expected US-3 and US-2, got %s and %s", stories[0].ID, stories[1].ID)
+	}
+
+	for _, story := range EligibleStories(prd, 10) {
+		if story.ID == "US-4" {
+			t.Error("Expected US-4 to wait for its dependency")
+		}
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	w := &prefixWriter{mu: &mu, out: &out, prefix: "[US-1] "}
+
+	w.Write([]byte("first line\nsecond "))
+	w.Write([]byte("line\npartial"))
+	w.Flush()
+
+	expected := "[US-1] first line\n[US-1] second line\n[US-1] partial\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestMergeStoryBranchConflict(t *testing.T) {
+	dir := initTestRepo(t)
+	runGit(dir, "checkout", "-b", "ralph/feature")
+	runGit(dir, "branch", "ralph/feature-US-1")
+
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("feature\n"), 0644)
+	runGit(dir, "commit", "-am", "feature change")
+
+	runGit(dir, "checkout", "ralph/feature-US-1")
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("story\n"), 0644)
+	runGit(dir, "commit", "-am", "story change")
+	runGit(dir, "checkout", "ralph/feature")
+
+	run := storyRun{Story: UserStory{ID: "US-1"}, Branch: "ralph/feature-US-1"}
+	var conflict *conflictError
+	if err := mergeStoryBranch(dir, run); !errors.As(err, &conflict) || len(conflict.Files) != 1 || conflict.Files[0] != "README.md" {
+		t.Fatalf("Expected merge conflict in README.md, got %v", err)
+	}
+
+	files, _ := DirtyFiles(dir)
+	if len(files) != 0 {
+		t.Errorf("Expected aborted merge to leave a clean tree, got %v", files)
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	// Fake agent: works on the assigned story, appends to the progress log,
+	// marks the story passing and commits everything like the prompt says
+	storyPattern := regexp.MustCompile(`Work ONLY on story (\S+)`)
+	agent := agentFunc(func(_ context.Context, agentDir string, prompt []byte, _, _ io.Writer) (string, error) {
+		match := storyPattern.FindSubmatch(prompt)
+		if match == nil {
+			return "", errors.New("no story in prompt")
+		}
+		id := string(match[1])
+		if err := os.WriteFile(filepath.Join(agentDir, id+".txt"), []byte(id+"\n"), 0644); err != nil {
+			return "", err
+		}
+
+		progress, err := os.OpenFile(filepath.Join(agentDir, ".ralph", "progress.txt"), os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(progress, "## %s\n- implemented %s\n", id, id)
+		progress.Close()
+
+		prdFile := filepath.Join(agentDir, ".ralph", "prd.yaml")
+		prd, err := LoadPRD(prdFile)
+		if err != nil {
+			return "", err
+		}
+		for i := range prd.UserStories {
+			if prd.UserStories[i].ID == id {
+				prd.UserStories[i].Passes = true
+			}
+		}
+		data, err := yaml.Marshal(prd)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(prdFile, data, 0644); err != nil {
+			return "", err
+		}
+
+		runGit(agentDir, "add", "-A")
+		_, err = runGit(agentDir, "commit", "-qm", "feat: "+id)
+		return "", err
+	})
+
+	for _, tracked := range []bool{false, true} {
+		t.Run(fmt.Sprintf("tracked ralph files %v", tracked), func(t *testing.T) {
+			dir := initTestRepo(t)
+			ralphDir := filepath.Join(dir, ".ralph")
+			os.MkdirAll(ralphDir, 0755)
+			os.WriteFile(filepath.Join(ralphDir, "prompt.md"), []byte("prompt"), 0644)
+			InitProgressFile(filepath.Join(ralphDir, "progress.txt"))
+			prd := &PRD{
+				BranchName: "ralph/feature",
+				UserStories: []UserStory{
+					{ID: "US-1", Priority: 1},
+					{ID: "US-2", Priority: 2},
+					{ID: "US-3", Priority: 1, DependsOn: []string{"US-1"}},
+				},
+			}
+			if err := SavePRD(filepath.Join(ralphDir, "prd.yaml"), prd); err != nil {
+				t.Fatalf("Failed to save PRD: %v", err)
+			}
+			if tracked {
+				runGit(dir, "add", ".ralph")
+				runGit(dir, "commit", "-m", "add ralph files")
+			}
+			runGit(dir, "checkout", "-b", "ralph/feature")
+
+			journal, _ := newJournal(ralphDir, time.Now())
+			runner := &Runner{
+				Config:   &Config{Tool: "fake", MaxIterations: 3, PromptFile: "prompt.md"},
+				WorkDir:  dir,
+				RalphDir: ralphDir,
+				Parallel: 2,
+				Agent:    agent,
+				Stdout:   io.Discard,
+				Stderr:   io.Discard,
+				Control:  &Control{},
+				skipped:  map[string]bool{},
+			}
+
+			complete, err := runner.runParallel(context.Background(), journal, "ralph/feature")
+			if err != nil {
+				t.Fatalf("runParallel failed: %v", err)
+			}
+			if !complete {
+				t.Fatal("Expected all stories to pass")
+			}
+
+			for _, id := range []string{"US-1", "US-2", "US-3"} {
+				if !fileExists(filepath.Join(dir, id+".txt")) {
+					t.Errorf("Expected %s to be merged", id)
+				}
+				if progress := readFile(filepath.Join(ralphDir, "progress.txt")); !strings.Contains(progress, "implemented "+id) {
+					t.Errorf("Expected progress of %s, got:\n%s", id, progress)
+				}
+			}
+			if len(journal.Records) != 2 {
+				t.Fatalf("Expected 2 rounds, got %d", len(journal.Records))
+			}
+			if len(journal.Records[0].Stories) != 2 || journal.Records[1].Stories[0] != "US-3" {
+				t.Errorf("Unexpected rounds: %v, %v", journal.Records[0].Stories, journal.Records[1].Stories)
+			}
+			for _, record := range journal.Records {
+				if len(record.Conflicts) > 0 {
+					t.Errorf("Expected no conflicts, got %v in round %d", record.Conflicts, record.Iteration)
+				}
+			}
+			if branch, _ := CurrentBranch(dir); branch != "ralph/feature" {
+				t.Errorf("Expected to stay on ralph/feature, got %s", branch)
+			}
+			if status, _ := runGit(dir, "status", "--porcelain", "--", ".ralph/prd.yaml", ".ralph/progress.txt"); tracked && status != "" {
+				t.Errorf("Expected the ralph files to be committed, got:\n%s", status)
+			}
+		})
+	}
+}
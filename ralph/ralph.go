@@ -0,0 +1,364 @@
+// Package ralph runs a coding agent in a loop over the user stories of a PRD
+// until they all pass. It is the engine behind the go-ralph command and can be
+// embedded in other tools through Runner.
+package ralph
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultBaseBranch is the branch a missing PRD branch is created from when
+// Config.BaseBranch is empty.
+const DefaultBaseBranch = "main"
+
+// Config is the content of .ralph/config.yaml.
+type Config struct {
+	Version           int                 `yaml:"version"`
+	Tool              string              `yaml:"tool"`
+	MaxIterations     int                 `yaml:"max_iterations"`
+	AutoArchive       bool                `yaml:"auto_archive"`
+	PromptFile        string              `yaml:"prompt_file"`
+	BaseBranch        string              `yaml:"base_branch"`
+	RollbackOnFailure bool                `yaml:"rollback_on_failure"`
+	RequireApproval   bool                `yaml:"require_approval,omitempty"`
+	TemplatesDir      string              `yaml:"templates_dir"`
+	ToolArgs          map[string][]string `yaml:"tool_args"`
+	Hooks             Hooks               `yaml:"hooks,omitempty"`
+	Notifications     Notifications       `yaml:"notifications,omitempty"`
+	Profiles          map[string]Profile  `yaml:"profiles,omitempty"`
+}
+
+// Profile holds config values that override the top-level ones when the
+// profile is selected with --profile or RALPH_PROFILE.
+type Profile map[string]any
+
+// ToolPromptFile returns prompt_file with a {tool} placeholder replaced by the
+// selected tool, for repos initialized with a prompt per tool.
+func (c *Config) ToolPromptFile() string {
+	return strings.ReplaceAll(c.PromptFile, "{tool}", c.Tool)
+}
+
+// BaseBranchOrDefault returns base_branch, or DefaultBaseBranch when unset.
+func (c *Config) BaseBranchOrDefault() string {
+	if c.BaseBranch == "" {
+		return DefaultBaseBranch
+	}
+	return c.BaseBranch
+}
+
+// PRD is the content of .ralph/prd.yaml.
+type PRD struct {
+	Version     int         `yaml:"version" json:"version"`
+	Project     string      `yaml:"project" json:"project"`
+	BranchName  string      `yaml:"branchName" json:"branchName"`
+	Description string      `yaml:"description" json:"description"`
+	UserStories []UserStory `yaml:"userStories" json:"userStories"`
+}
+
+type UserStory struct {
+	ID                 string   `yaml:"id" json:"id"`
+	Title              string   `yaml:"title" json:"title"`
+	Description        string   `yaml:"description" json:"description"`
+	AcceptanceCriteria []string `yaml:"acceptanceCriteria" json:"acceptanceCriteria"`
+	Priority           int      `yaml:"priority" json:"priority"`
+	Passes             bool     `yaml:"passes" json:"passes"`
+	Notes              string   `yaml:"notes" json:"notes"`
+	DependsOn          []string `yaml:"dependsOn,omitempty" json:"dependsOn,omitempty"`
+	RequireApproval    bool     `yaml:"requireApproval,omitempty" json:"requireApproval,omitempty"`
+}
+
+func LoadPRD(path string) (*PRD, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var prd PRD
+	if err := yaml.Unmarshal(data, &prd); err != nil {
+		return nil, err
+	}
+
+	return &prd, nil
+}
+
+// SavePRD writes prd to path as a new document. Use SetStoryPasses to update
+// an existing PRD without losing its comments.
+func SavePRD(path string, prd *PRD) error {
+	data, err := yaml.Marshal(prd)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// SetStoryPasses sets passes on the story with id in the PRD at path.
+func SetStoryPasses(path, id string, passes bool) error {
+	return updateStory(path, id, map[string]*yaml.Node{"passes": boolNode(passes)})
+}
+
+// updateStory sets fields of the story with id in the PRD at path. Only the
+// lines of the edited values are rewritten, so comments, formatting and keys
+// go-ralph doesn't know about survive and the diff stays small.
+func updateStory(path, id string, fields map[string]*yaml.Node) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Each edit moves the lines below it, so the document is parsed again
+	for _, key := range keys {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			return fmt.Errorf("%s: expected a mapping at the top level", path)
+		}
+
+		story := findStoryNode(doc.Content[0], id)
+		if story == nil {
+			return fmt.Errorf("%s: story '%s' not found", path, id)
+		}
+		if edited, ok := setMappingText(data, story, key, fields[key]); ok {
+			data = edited
+			continue
+		}
+
+		// Flow style stories can't be edited line by line
+		setMappingValue(story, key, fields[key])
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&doc); err != nil {
+			return err
+		}
+		encoder.Close()
+		data = buf.Bytes()
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// findStoryNode returns the mapping of the story with id in userStories.
+func findStoryNode(root *yaml.Node, id string) *yaml.Node {
+	stories := mappingValue(root, "userStories")
+	if stories == nil || stories.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, story := range stories.Content {
+		if story.Kind != yaml.MappingNode {
+			continue
+		}
+		if value := mappingValue(story, "id"); value != nil && value.Value == id {
+			return story
+		}
+	}
+	return nil
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces the value of key, keeping the comments around it,
+// or adds the key at the end when it is missing.
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			old := mapping.Content[i+1]
+			value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// setMappingText sets key to value in mapping, a node parsed from data, and
+// returns the edited text. A plain value is swapped in place, keeping the
+// comment after it. Other values replace the lines of the old entry, and
+// missing keys are added after the last entry with the same indentation. It
+// reports false for flow style mappings it can't edit.
+func setMappingText(data []byte, mapping *yaml.Node, key string, value *yaml.Node) ([]byte, bool) {
+	lines := strings.SplitAfter(string(data), "\n")
+	flow := mapping.Style&yaml.FlowStyle != 0
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keyNode, old := mapping.Content[i], mapping.Content[i+1]
+		if keyNode.Value != key {
+			continue
+		}
+
+		text, err := yaml.Marshal(value)
+		if err == nil && old.Kind == yaml.ScalarNode && old.Style == 0 && strings.Count(string(text), "\n") == 1 {
+			line := lines[old.Line-1]
+			start := columnOffset(line, old.Column)
+			if strings.HasPrefix(line[start:], old.Value) {
+				lines[old.Line-1] = line[:start] + strings.TrimSuffix(string(text), "\n") + line[start+len(old.Value):]
+				return []byte(strings.Join(lines, "")), true
+			}
+		}
+		if flow {
+			return nil, false
+		}
+
+		if value.LineComment == "" {
+			value.LineComment = old.LineComment
+		}
+		entry, ok := encodeEntry(key, value, keyNode.Column-1)
+		if !ok {
+			return nil, false
+		}
+		line := lines[keyNode.Line-1]
+		entry = line[:columnOffset(line, keyNode.Column)] + entry
+		return spliceLines(lines, keyNode.Line-1, entryEnd(lines, keyNode)+1, entry), true
+	}
+
+	if flow || len(mapping.Content) == 0 {
+		return nil, false
+	}
+	indent := mapping.Content[0].Column - 1
+	entry, ok := encodeEntry(key, value, indent)
+	if !ok {
+		return nil, false
+	}
+	end := entryEnd(lines, mapping.Content[len(mapping.Content)-2]) + 1
+	return spliceLines(lines, end, end, strings.Repeat(" ", indent)+entry), true
+}
+
+// encodeEntry encodes key and value as a block mapping entry whose lines
+// after the first are indented by indent spaces.
+func encodeEntry(key string, value *yaml.Node, indent int) (string, bool) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{stringNode(key), value}}); err != nil {
+		return "", false
+	}
+	encoder.Close()
+
+	lines := strings.SplitAfter(buf.String(), "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = strings.Repeat(" ", indent) + lines[i]
+		}
+	}
+	return strings.Join(lines, ""), true
+}
+
+// entryEnd returns the index of the last line of the block mapping entry
+// that starts with key: the lines below it indented deeper than the key, or
+// as deep when they are items of a sequence value.
+func entryEnd(lines []string, key *yaml.Node) int {
+	indent := key.Column - 1
+	end := key.Line - 1
+	for i := key.Line; i < len(lines); i++ {
+		content := strings.TrimLeft(lines[i], " ")
+		depth := len(lines[i]) - len(content)
+		content = strings.TrimRight(content, "\r\n")
+		if content == "" {
+			continue
+		}
+		if depth < indent || depth == indent && content != "-" && !strings.HasPrefix(content, "- ") {
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+// spliceLines replaces lines[from:to] with text.
+func spliceLines(lines []string, from, to int, text string) []byte {
+	var buf strings.Builder
+	for _, line := range lines[:from] {
+		buf.WriteString(line)
+	}
+	if from > 0 && !strings.HasSuffix(lines[from-1], "\n") {
+		buf.WriteString("\n")
+	}
+	buf.WriteString(text)
+	for _, line := range lines[to:] {
+		buf.WriteString(line)
+	}
+	return []byte(buf.String())
+}
+
+// columnOffset converts a 1-based yaml.Node column, counted in characters,
+// to a byte offset in line.
+func columnOffset(line string, column int) int {
+	n := 0
+	for offset := range line {
+		if n == column-1 {
+			return offset
+		}
+		n++
+	}
+	return len(line)
+}
+
+func boolNode(b bool) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(b)}
+}
+
+func stringNode(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// InitProgressFile starts a fresh progress log at path.
+func InitProgressFile(path string) {
+	content := fmt.Sprintf("# Ralph Progress Log\nStarted: %s\n---\n", time.Now().Format(time.RFC1123))
+	writeFile(path, content)
+}
+
+func getBranchFromPRD(prdFile string) string {
+	prd, err := LoadPRD(prdFile)
+	if err != nil {
+		return ""
+	}
+
+	return prd.BranchName
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func readFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func writeFile(path, content string) error {
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
@@ -0,0 +1,132 @@
+package ralph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestToolPromptFile(t *testing.T) {
+	config := &Config{Tool: "copilot", PromptFile: "prompt.{tool}.md"}
+	if config.ToolPromptFile() != "prompt.copilot.md" {
+		t.Errorf("Expected prompt.copilot.md, got %s", config.ToolPromptFile())
+	}
+	config.PromptFile = "prompt.md"
+	if config.ToolPromptFile() != "prompt.md" {
+		t.Errorf("Expected prompt.md, got %s", config.ToolPromptFile())
+	}
+}
+
+func TestBaseBranchOrDefault(t *testing.T) {
+	config := &Config{}
+	if config.BaseBranchOrDefault() != DefaultBaseBranch {
+		t.Errorf("Expected %s, got %s", DefaultBaseBranch, config.BaseBranchOrDefault())
+	}
+	config.BaseBranch = "develop"
+	if config.BaseBranchOrDefault() != "develop" {
+		t.Errorf("Expected develop, got %s", config.BaseBranchOrDefault())
+	}
+}
+
+func TestSetStoryPasses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prd.yaml")
+	os.WriteFile(path, []byte(`# Feature PRD
+project: Test
+branchName: ralph/feature
+owner: team-a # not a go-ralph key
+userStories:
+# Do this first
+- id: US-1
+  title: First
+  acceptanceCriteria:
+  - Works
+  passes: false # flipped by go-ralph
+- id: US-2
+  title: Second
+  notes: Old note
+  acceptanceCriteria:
+      - Indented deeper
+
+`), 0644)
+
+	if err := SetStoryPasses(path, "US-1", true); err != nil {
+		t.Fatalf("SetStoryPasses failed: %v", err)
+	}
+	if err := updateStory(path, "US-2", map[string]*yaml.Node{"passes": boolNode(false), "notes": stringNode("Add tests\nAnd docs")}); err != nil {
+		t.Fatalf("updateStory failed: %v", err)
+	}
+
+	expected := `# Feature PRD
+project: Test
+branchName: ralph/feature
+owner: team-a # not a go-ralph key
+userStories:
+# Do this first
+- id: US-1
+  title: First
+  acceptanceCriteria:
+  - Works
+  passes: true # flipped by go-ralph
+- id: US-2
+  title: Second
+  notes: |-
+    Add tests
+    And docs
+  acceptanceCriteria:
+      - Indented deeper
+  passes: false
+
+`
+	data, _ := os.ReadFile(path)
+	if string(data) != expected {
+		t.Errorf("Expected only the edited values to change, got:\n%s", data)
+	}
+
+	prd, err := LoadPRD(path)
+	if err != nil {
+		t.Fatalf("LoadPRD failed: %v", err)
+	}
+	if !prd.UserStories[0].Passes || prd.UserStories[1].Passes || prd.UserStories[1].Notes != "Add tests\nAnd docs" {
+		t.Errorf("Unexpected stories: %+v", prd.UserStories)
+	}
+
+	if err := SetStoryPasses(path, "US-9", true); err == nil {
+		t.Error("Expected an error for an unknown story")
+	}
+
+	t.Run("flow style story", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "prd.yaml")
+		os.WriteFile(path, []byte("userStories:\n  - {id: US-1, title: First}\n"), 0644)
+
+		if err := updateStory(path, "US-1", map[string]*yaml.Node{"passes": boolNode(true), "notes": stringNode("Done")}); err != nil {
+			t.Fatalf("updateStory failed: %v", err)
+		}
+		prd, err := LoadPRD(path)
+		if err != nil || !prd.UserStories[0].Passes || prd.UserStories[0].Notes != "Done" {
+			t.Errorf("Expected the flow style story to be updated, got %+v, %v", prd, err)
+		}
+	})
+}
+
+func TestArchive(t *testing.T) {
+	ralphDir := t.TempDir()
+	os.WriteFile(filepath.Join(ralphDir, "prd.yaml"), []byte("project: Test\n"), 0644)
+	os.WriteFile(filepath.Join(ralphDir, "progress.txt"), []byte("# Ralph Progress Log\n"), 0644)
+
+	folder, err := Archive(ralphDir, "ralph/feature")
+	if err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	if !strings.HasSuffix(folder, "-ralph/feature") {
+		t.Errorf("Unexpected archive folder: %s", folder)
+	}
+	if readFile(filepath.Join(folder, "prd.yaml")) != "project: Test" {
+		t.Error("Expected prd.yaml to be archived")
+	}
+	if !fileExists(filepath.Join(folder, "progress.txt")) {
+		t.Error("Expected progress.txt to be archived")
+	}
+}
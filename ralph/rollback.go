@@ -0,0 +1,129 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runStatePaths is the pathspec of the working tree minus the files go-ralph
+// writes while a run is in progress. Snapshots leave those alone so a
+// rollback never touches the journal, the lock or the story worktrees.
+var runStatePaths = []string{
+	".",
+	":(exclude).ralph/runs",
+	":(exclude).ralph/worktrees",
+	":(exclude).ralph/" + LockFile + "*",
+	":(exclude).ralph/" + SocketFile,
+	":(exclude).ralph/.last-branch",
+}
+
+// Snapshot records the state of the working tree before an iteration so a
+// failed iteration can be rolled back. Tree holds every file, untracked ones
+// and uncommitted changes to the PRD, config and progress log included, and
+// Index what was staged.
+type Snapshot struct {
+	Head  string
+	Tree  string
+	Index string
+}
+
+func takeSnapshot(dir string) (*Snapshot, error) {
+	head, err := gitHead(dir)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := writeWorktreeTree(dir)
+	if err != nil {
+		return nil, err
+	}
+	index, err := runGit(dir, "write-tree")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{Head: head, Tree: tree, Index: index}, nil
+}
+
+// writeWorktreeTree stores the working tree as a git tree, untracked files
+// included. It stages into a copy of the index so the real one is left alone.
+func writeWorktreeTree(dir string) (string, error) {
+	scratch, err := os.MkdirTemp("", "ralph-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(scratch)
+
+	indexFile, err := runGit(dir, "rev-parse", "--git-path", "index")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(indexFile) {
+		indexFile = filepath.Join(dir, indexFile)
+	}
+	scratchIndex := filepath.Join(scratch, "index")
+	if fileExists(indexFile) {
+		if err := copyFile(indexFile, scratchIndex); err != nil {
+			return "", err
+		}
+	}
+
+	env := []string{"GIT_INDEX_FILE=" + scratchIndex}
+	if _, err := runGitEnv(dir, env, append([]string{"add", "--all", "--"}, runStatePaths...)...); err != nil {
+		return "", err
+	}
+	return runGitEnv(dir, env, "write-tree")
+}
+
+// Diff returns everything that changed since the snapshot, committed or not,
+// including untracked files, as a binary patch.
+func (s *Snapshot) Diff(dir string) (string, error) {
+	tree, err := writeWorktreeTree(dir)
+	if err != nil {
+		return "", err
+	}
+	patch, err := runGit(dir, "diff", "--binary", s.Tree, tree)
+	if err != nil {
+		return "", err
+	}
+	if patch != "" {
+		patch += "\n"
+	}
+	return patch, nil
+}
+
+// Restore saves the discarded changes to patchPath (when there are any) and
+// resets the working tree and index to the snapshot.
+func (s *Snapshot) Restore(dir, patchPath string) error {
+	patch, err := s.Diff(dir)
+	if err != nil {
+		return fmt.Errorf("failed to compute discarded diff: %w", err)
+	}
+	if patch != "" {
+		if err := writeFile(patchPath, patch); err != nil {
+			return fmt.Errorf("failed to save discarded diff: %w", err)
+		}
+	}
+
+	if _, err := runGit(dir, "reset", "--hard", s.Head); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, append([]string{"clean", "-fd", "--"}, runStatePaths...)...); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, "read-tree", "-u", "--reset", s.Tree); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, "read-tree", s.Index); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// iterationFailed reports whether an iteration should be rolled back: the
+// tool exited with an error or left uncommitted changes behind. Changes that
+// were there before the iteration are not in record.Uncommitted.
+func iterationFailed(record IterationRecord) bool {
+	return record.ExitCode != 0 || len(record.Uncommitted) > 0
+}
@@ -0,0 +1,200 @@
+package ralph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	t.Run("discards commits and new files", func(t *testing.T) {
+		dir := initTestRepo(t)
+		os.MkdirAll(filepath.Join(dir, ".ralph"), 0755)
+		os.WriteFile(filepath.Join(dir, ".ralph", "progress.txt"), []byte("log\n"), 0644)
+
+		snapshot, err := takeSnapshot(dir)
+		if err != nil {
+			t.Fatalf("takeSnapshot failed: %v", err)
+		}
+
+		os.WriteFile(filepath.Join(dir, "README.md"), []byte("broken\n"), 0644)
+		runGit(dir, "commit", "-am", "broken commit")
+		os.WriteFile(filepath.Join(dir, "wip.go"), []byte("package wip\n"), 0644)
+
+		patchPath := filepath.Join(t.TempDir(), "iteration-1.patch")
+		if err := snapshot.Restore(dir, patchPath); err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+
+		head, _ := gitHead(dir)
+		if head != snapshot.Head {
+			t.Errorf("Expected HEAD %s, got %s", snapshot.Head, head)
+		}
+		if fileExists(filepath.Join(dir, "wip.go")) {
+			t.Error("Expected untracked file to be removed")
+		}
+		if readFile(filepath.Join(dir, "README.md")) != "test" {
+			t.Error("Expected README.md to be restored")
+		}
+		if !fileExists(filepath.Join(dir, ".ralph", "progress.txt")) {
+			t.Error("Expected .ralph files to be kept")
+		}
+
+		patch := readFile(patchPath)
+		if !strings.Contains(patch, "+broken") || !strings.Contains(patch, "wip.go") {
+			t.Errorf("Expected patch to contain discarded changes, got:\n%s", patch)
+		}
+	})
+
+	t.Run("restores changes from before the iteration", func(t *testing.T) {
+		dir := initTestRepo(t)
+		configFile := filepath.Join(dir, ".ralph", "config.yaml")
+		os.MkdirAll(filepath.Dir(configFile), 0755)
+		os.WriteFile(configFile, []byte("max_iterations: 10\n"), 0644)
+		runGit(dir, "add", ".ralph")
+		runGit(dir, "commit", "-m", "add config")
+
+		os.WriteFile(filepath.Join(dir, "README.md"), []byte("user edit\n"), 0644)
+		os.WriteFile(configFile, []byte("max_iterations: 5\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "staged.txt"), []byte("staged\n"), 0644)
+		runGit(dir, "add", "staged.txt")
+
+		snapshot, err := takeSnapshot(dir)
+		if err != nil {
+			t.Fatalf("takeSnapshot failed: %v", err)
+		}
+		if readFile(filepath.Join(dir, "README.md")) != "user edit" {
+			t.Fatal("Expected snapshot to leave the working tree untouched")
+		}
+
+		os.WriteFile(filepath.Join(dir, "README.md"), []byte("agent edit\n"), 0644)
+		os.WriteFile(configFile, []byte("max_iterations: 1\n"), 0644)
+		runGit(dir, "commit", "-am", "agent commit")
+
+		patchPath := filepath.Join(t.TempDir(), "iteration.patch")
+		if err := snapshot.Restore(dir, patchPath); err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+		if readFile(filepath.Join(dir, "README.md")) != "user edit" {
+			t.Error("Expected user edit to be restored")
+		}
+		if readFile(configFile) != "max_iterations: 5" {
+			t.Errorf("Expected config edit to be restored, got %q", readFile(configFile))
+		}
+		if !fileExists(filepath.Join(dir, "notes.txt")) {
+			t.Error("Expected untracked user file to be restored")
+		}
+		if staged, _ := runGit(dir, "diff", "--cached", "--name-only"); staged != "staged.txt" {
+			t.Errorf("Expected staged.txt to stay staged, got %q", staged)
+		}
+
+		patch := readFile(patchPath)
+		if !strings.Contains(patch, "+agent edit") || strings.Contains(patch, "+user edit") || strings.Contains(patch, "notes.txt") {
+			t.Errorf("Expected patch to contain only the iteration's changes, got:\n%s", patch)
+		}
+	})
+}
+
+func TestIterationFailed(t *testing.T) {
+	if iterationFailed(IterationRecord{}) {
+		t.Error("Expected clean iteration to pass")
+	}
+	if !iterationFailed(IterationRecord{ExitCode: 1}) {
+		t.Error("Expected non-zero exit code to fail")
+	}
+	if !iterationFailed(IterationRecord{Uncommitted: []string{"a.go"}}) {
+		t.Error("Expected uncommitted changes to fail")
+	}
+}
+
+func TestRunnerRollback(t *testing.T) {
+	t.Run("agent switches branch", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		config.RollbackOnFailure = true
+		mainHead, _ := gitHead(dir)
+
+		runner := &Runner{Config: config, WorkDir: dir, Agent: agentFunc(func(_ context.Context, agentDir string, _ []byte, _, _ io.Writer) (string, error) {
+			runGit(agentDir, "checkout", "-q", "main")
+			os.WriteFile(filepath.Join(agentDir, "main.txt"), []byte("on main\n"), 0644)
+			runGit(agentDir, "add", "main.txt")
+			runGit(agentDir, "commit", "-m", "commit on main")
+			return "", errors.New("exit status 1")
+		})}
+
+		result, err := runner.Run(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "switched branches") || len(result.Records) != 1 {
+			t.Fatalf("Expected branch switch error after 1 iteration, got %v, %+v", err, result)
+		}
+		if result.Records[0].RolledBack {
+			t.Error("Expected no rollback on another branch")
+		}
+		commits, _ := commitsBetween(dir, mainHead, "main")
+		if len(commits) != 1 || commits[0].Subject != "commit on main" {
+			t.Errorf("Expected main to keep the agent's commit, got %+v", commits)
+		}
+	})
+
+	t.Run("failed iteration", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		config.RollbackOnFailure = true
+
+		calls := 0
+		runner := &Runner{Config: config, WorkDir: dir, Agent: agentFunc(func(_ context.Context, agentDir string, _ []byte, _, _ io.Writer) (string, error) {
+			calls++
+			if calls == 1 {
+				os.WriteFile(filepath.Join(agentDir, "wip.txt"), []byte("wip\n"), 0644)
+				return "", errors.New("exit status 1")
+			}
+			os.WriteFile(filepath.Join(agentDir, "done.txt"), []byte("done\n"), 0644)
+			runGit(agentDir, "add", "done.txt")
+			runGit(agentDir, "commit", "-m", "feat: done")
+			return CompleteSignal, nil
+		})}
+
+		result, err := runner.Run(context.Background())
+		if err != nil || !result.Complete || len(result.Records) != 2 {
+			t.Fatalf("Expected completion after 2 iterations, got %v, %+v", err, result)
+		}
+		if !result.Records[0].RolledBack || result.Records[1].RolledBack {
+			t.Errorf("Expected only iteration 1 to be rolled back, got %+v", result.Records)
+		}
+		if fileExists(filepath.Join(dir, "wip.txt")) {
+			t.Error("Expected wip.txt to be discarded")
+		}
+	})
+
+	t.Run("dirty before the run", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		config.RollbackOnFailure = true
+		os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes\n"), 0644)
+
+		calls := 0
+		runner := &Runner{Config: config, WorkDir: dir, AllowDirty: true, Agent: agentFunc(func(_ context.Context, agentDir string, _ []byte, _, _ io.Writer) (string, error) {
+			calls++
+			file := fmt.Sprintf("step-%d.txt", calls)
+			os.WriteFile(filepath.Join(agentDir, file), []byte("done\n"), 0644)
+			runGit(agentDir, "add", file)
+			runGit(agentDir, "commit", "-m", "feat: "+file)
+			return "", nil
+		})}
+
+		result, err := runner.Run(context.Background())
+		if err != nil || len(result.Records) != 3 {
+			t.Fatalf("Expected 3 iterations, got %v, %+v", err, result)
+		}
+		for _, record := range result.Records {
+			if record.RolledBack || len(record.Uncommitted) > 0 {
+				t.Errorf("Expected iteration %d to keep its commit, got %+v", record.Iteration, record)
+			}
+		}
+		if !fileExists(filepath.Join(dir, "notes.txt")) {
+			t.Error("Expected notes.txt to be kept")
+		}
+	})
+}
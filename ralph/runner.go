@@ -0,0 +1,470 @@
+package ralph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CompleteSignal is printed by the agent once every story passes.
+const CompleteSignal = "<promise>COMPLETE</promise>"
+
+// LockFile is the name of the lock file held in the ralph directory while a
+// run is in progress.
+const LockFile = "ralph.lock"
+
+// Runner runs the agent loop for a checkout. Config and WorkDir are required,
+// the other fields are optional.
+type Runner struct {
+	Config *Config
+	// WorkDir is the root of the git checkout the agent works in.
+	WorkDir string
+	// RalphDir holds the PRD, prompt and run state, WorkDir/.ralph by default.
+	RalphDir string
+	// Parallel works on up to this many independent stories at once, each in
+	// its own git worktree, when greater than 1.
+	Parallel int
+	// AllowDirty starts even when the working tree has uncommitted changes.
+	AllowDirty bool
+	// Agent runs the iterations, by default the command named by Config.Tool
+	// with its tool_args.
+	Agent Agent
+	// Stdout and Stderr receive the agent's output, discarded when nil.
+	Stdout io.Writer
+	Stderr io.Writer
+	// OnEvent is called synchronously as the run progresses.
+	OnEvent func(Event)
+	// OnSpan is called synchronously with each span of the run as it ends,
+	// children before their parents.
+	OnSpan func(Span)
+	// Notifiers announce the end of the run, by default the ones enabled in
+	// Config.Notifications writing to Stdout.
+	Notifiers []Notifier
+	// Control pauses, stops or skips stories of the run between iterations.
+	Control *Control
+	// Approver reviews the stories that pass when Config.RequiresApproval
+	// says so. Runs needing approval fail without one.
+	Approver Approver
+	// Lock is the run lock when the caller already holds it, e.g. to migrate
+	// files before the run. Run acquires and releases its own when nil.
+	Lock *RunLock
+	// ControlSocket is the path of a Unix socket that accepts ControlRequests
+	// for Control while the run is in progress, none when empty.
+	ControlSocket string
+	// Delay pauses between iterations.
+	Delay time.Duration
+
+	runID         string
+	branch        string
+	webhooks      []*webhookSender
+	skipped       map[string]bool
+	stopped       bool
+	traceID       string
+	runSpan       *Span
+	iterationSpan *Span
+}
+
+// StopReason tells why a run ended.
+type StopReason string
+
+const (
+	StopComplete      StopReason = "complete"
+	StopMaxIterations StopReason = "max_iterations"
+	StopError         StopReason = "error"
+	StopInterrupted   StopReason = "interrupted"
+	// StopRequested means the run was stopped through Control, or every
+	// remaining story was skipped.
+	StopRequested StopReason = "stopped"
+)
+
+// Result summarizes a finished run.
+type Result struct {
+	RunID         string            `json:"run_id"`
+	Dir           string            `json:"dir"`
+	Complete      bool              `json:"complete"`
+	Reason        StopReason        `json:"reason"`
+	Duration      time.Duration     `json:"duration"`
+	StoriesPassed int               `json:"stories_passed"`
+	StoriesTotal  int               `json:"stories_total"`
+	Records       []IterationRecord `json:"records"`
+	// Usage totals the tokens of the iterations whose tool reported them.
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// DirtyTreeError is returned by Run when the working tree has uncommitted
+// changes and AllowDirty is not set.
+type DirtyTreeError struct {
+	Files []string
+}
+
+func (e *DirtyTreeError) Error() string {
+	return fmt.Sprintf("working tree has %d uncommitted change(s)", len(e.Files))
+}
+
+// Run works through the PRD until the agent signals that every story passes,
+// Config.MaxIterations is reached, ctx is cancelled or an error stops the run.
+// Result holds the iterations run so far in every case.
+func (r *Runner) Run(ctx context.Context) (Result, error) {
+	if r.Config == nil {
+		return Result{}, errors.New("ralph: Runner.Config is required")
+	}
+	if r.RalphDir == "" {
+		r.RalphDir = filepath.Join(r.WorkDir, ".ralph")
+	}
+	if r.Agent == nil {
+		r.Agent = CommandAgent{Command: r.Config.Tool, Args: r.Config.ToolArgs[r.Config.Tool]}
+	}
+	if r.Stdout == nil {
+		r.Stdout = io.Discard
+	}
+	if r.Stderr == nil {
+		r.Stderr = io.Discard
+	}
+	if r.Notifiers == nil {
+		r.Notifiers = r.Config.Notifications.Notifiers(r.Stdout)
+	}
+	if r.Control == nil {
+		r.Control = &Control{}
+	}
+	r.skipped = map[string]bool{}
+	startedAt := time.Now()
+
+	// Only one run per checkout at a time
+	if r.Lock == nil {
+		lock, stale, err := AcquireLock(filepath.Join(r.RalphDir, LockFile))
+		if err != nil {
+			return Result{}, err
+		}
+		defer lock.Release()
+		if stale != nil {
+			r.emit(Event{Type: EventWarning, Message: fmt.Sprintf("removed stale lock %s", filepath.Join(r.RalphDir, LockFile))})
+		}
+	}
+
+	// Holding the lock, the socket left by an earlier run can be replaced
+	if r.ControlSocket != "" {
+		if closeControl, err := ServeControl(r.ControlSocket, r.Control); err != nil {
+			r.emit(Event{Type: EventWarning, Message: fmt.Sprintf("failed to listen for control requests: %v", err)})
+		} else {
+			defer closeControl()
+		}
+	}
+
+	prdFile := filepath.Join(r.RalphDir, "prd.yaml")
+	progressFile := filepath.Join(r.RalphDir, "progress.txt")
+	branchName := getBranchFromPRD(prdFile)
+	r.branch = branchName
+
+	r.startWebhooks()
+	defer r.closeWebhooks()
+
+	// Make sure the working tree is clean and on the PRD branch before
+	// touching the progress log, so a refused run leaves it alone
+	if r.Parallel > 1 && branchName == "" {
+		return Result{}, errors.New("parallel mode requires a PRD with branchName")
+	}
+	if branchName != "" {
+		if !r.AllowDirty {
+			files, err := DirtyFiles(r.WorkDir)
+			if err != nil {
+				return Result{}, fmt.Errorf("checking working tree: %w", err)
+			}
+			if len(files) > 0 {
+				return Result{}, &DirtyTreeError{Files: files}
+			}
+		}
+
+		created, err := ensureBranch(r.WorkDir, branchName, r.Config.BaseBranchOrDefault())
+		if err != nil {
+			return Result{}, fmt.Errorf("checking out branch '%s': %w", branchName, err)
+		}
+		if created {
+			r.emit(Event{Type: EventBranchCreated, Message: fmt.Sprintf("Created branch %s from %s", branchName, r.Config.BaseBranchOrDefault())})
+		}
+	}
+	// Archive previous run if branch changed
+	if lastBranch := PendingArchive(r.RalphDir); lastBranch != "" {
+		if archiveFolder, err := Archive(r.RalphDir, lastBranch); err != nil {
+			r.emit(Event{Type: EventWarning, Message: fmt.Sprintf("failed to create archive folder: %v", err)})
+		} else {
+			r.emit(Event{Type: EventArchived, Message: fmt.Sprintf("Archived previous run %s to %s", lastBranch, archiveFolder)})
+		}
+
+		// Reset progress file for new run
+		InitProgressFile(progressFile)
+	}
+
+	// Track current branch
+	if branchName != "" {
+		writeFile(filepath.Join(r.RalphDir, ".last-branch"), branchName)
+	}
+
+	// Initialize progress file if it doesn't exist
+	if !fileExists(progressFile) {
+		InitProgressFile(progressFile)
+	}
+
+	journal, err := newJournal(r.RalphDir, time.Now())
+	if err != nil {
+		return Result{}, fmt.Errorf("creating run directory: %w", err)
+	}
+	r.runID = journal.RunID
+	r.traceID = randomHex(16)
+	r.runSpan = r.startSpan("run", nil, map[string]any{"ralph.run_id": r.runID, "ralph.tool": r.Config.Tool, "ralph.branch": branchName})
+	r.emit(Event{Type: EventRunStarted, Message: fmt.Sprintf("Starting Ralph - Tool: %s - Max iterations: %d", r.Config.Tool, r.Config.MaxIterations)})
+
+	result := Result{RunID: journal.RunID, Dir: journal.Dir}
+	err = r.runHook(ctx, HookPreRun, hookEnv{})
+	if err == nil {
+		if r.Parallel > 1 {
+			result.Complete, err = r.runParallel(ctx, journal, branchName)
+		} else {
+			result.Complete, err = r.runSequential(ctx, journal, branchName)
+		}
+		r.endIterationSpan(err)
+	}
+	result.Records = journal.Records
+	result.Usage = sumUsage(result.Records)
+	result.Duration = time.Since(startedAt)
+	switch {
+	case result.Complete:
+		result.Reason = StopComplete
+	case ctx.Err() != nil:
+		result.Reason = StopInterrupted
+	case err != nil:
+		result.Reason = StopError
+	case r.stopped:
+		result.Reason = StopRequested
+	default:
+		result.Reason = StopMaxIterations
+	}
+	if prd, err := LoadPRD(prdFile); err == nil {
+		result.StoriesTotal = len(prd.UserStories)
+		for _, story := range prd.UserStories {
+			if story.Passes {
+				result.StoriesPassed++
+			}
+		}
+	}
+
+	r.runFinalHook(ctx, result, err)
+	notification := newNotification(branchName, result, err)
+	r.notify(ctx, notification)
+
+	r.runSpan.Attributes["ralph.reason"] = string(result.Reason)
+	r.runSpan.Attributes["ralph.iterations"] = len(result.Records)
+	r.runSpan.Attributes["ralph.stories_passed"] = result.StoriesPassed
+	r.runSpan.Attributes["ralph.stories_total"] = result.StoriesTotal
+	setUsageAttributes(r.runSpan.Attributes, result.Usage)
+	r.endSpan(r.runSpan, err)
+
+	r.emit(Event{Type: EventRunFinished, Result: &result, Message: notification.Title + ": " + notification.Message})
+	return result, err
+}
+
+// runSequential runs one agent at a time on the checkout. It returns true
+// when the agent signals completion.
+func (r *Runner) runSequential(ctx context.Context, journal *Journal, branchName string) (bool, error) {
+	prdFile := filepath.Join(r.RalphDir, "prd.yaml")
+
+	for i := 1; i <= r.Config.MaxIterations; i++ {
+		if i > 1 && r.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(r.Delay):
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		if ok, err := r.checkpoint(ctx, i); !ok {
+			return false, err
+		}
+
+		// The agent is expected to pick the highest priority story left
+		var stories []string
+		if prd, err := LoadPRD(prdFile); err == nil {
+			if r.onlySkippedLeft(prd) {
+				r.stopped = true
+				r.emit(Event{Type: EventWarning, Iteration: i, Message: "every story left is skipped or depends on a skipped story"})
+				return false, nil
+			}
+			for _, story := range r.eligibleStories(prd, 1) {
+				stories = append(stories, story.ID)
+			}
+		}
+
+		r.startIterationSpan(i, stories)
+
+		// The hook runs first so it can prepare the checkout and the prompt
+		if ok, err := r.preIteration(ctx, i, stories); err != nil {
+			return false, err
+		} else if !ok {
+			r.iterationSpan.Attributes["ralph.skipped"] = true
+			r.endIterationSpan(nil)
+			continue
+		}
+
+		prompt, err := os.ReadFile(filepath.Join(r.RalphDir, r.Config.ToolPromptFile()))
+		if err != nil {
+			return false, fmt.Errorf("reading prompt: %w", err)
+		}
+
+		prompt = r.skipPrompt(prompt)
+		r.emit(Event{Type: EventIterationStarted, Iteration: i, Stories: stories})
+
+		record := IterationRecord{Iteration: i, StartedAt: time.Now(), Stories: stories}
+		record.HeadBefore, _ = gitHead(r.WorkDir)
+		dirtyBefore, _ := dirtyState(r.WorkDir)
+		passing := passingStories(prdFile)
+
+		var snapshot *Snapshot
+		if r.Config.RollbackOnFailure {
+			snapshot, err = takeSnapshot(r.WorkDir)
+			if err != nil {
+				r.emit(Event{Type: EventWarning, Iteration: i, Message: fmt.Sprintf("failed to snapshot working tree, rollback disabled for this iteration: %v", err)})
+			}
+		}
+
+		// Run the agent with the ralph prompt. Errors only end the iteration,
+		// the agent's output already shows them.
+		tool := r.startSpan("tool "+r.Config.Tool, r.iterationSpan, map[string]any{"ralph.tool": r.Config.Tool, "ralph.story_ids": stories})
+		output, agentErr := r.Agent.Run(ctx, r.WorkDir, prompt, r.Stdout, r.Stderr)
+		record.ExitCode = exitCode(agentErr)
+		record.Duration = time.Since(record.StartedAt)
+		if usage, ok := ParseUsage(output); ok {
+			record.Usage = &usage
+		}
+		tool.Attributes["ralph.exit_code"] = record.ExitCode
+		setUsageAttributes(tool.Attributes, record.Usage)
+		r.endSpan(tool, agentErr)
+
+		// Record what the iteration changed in the repository
+		captureIteration(r.WorkDir, &record, dirtyBefore)
+		if ctx.Err() != nil {
+			r.appendRecord(journal, record)
+			return false, ctx.Err()
+		}
+
+		// The agent must stay on the PRD branch. This is checked before any
+		// rollback or review, which would otherwise rewrite the other branch.
+		if branchName != "" {
+			current, err := CurrentBranch(r.WorkDir)
+			switch {
+			case err != nil:
+				err = fmt.Errorf("checking current branch: %w", err)
+			case current != branchName:
+				err = fmt.Errorf("agent switched branches during iteration %d (expected '%s', now on '%s')", i, branchName, current)
+			}
+			if err != nil {
+				r.appendRecord(journal, record)
+				return false, err
+			}
+		}
+		if len(record.Uncommitted) > 0 {
+			r.emit(Event{Type: EventWarning, Iteration: i, Message: fmt.Sprintf("iteration %d left %d uncommitted change(s)", i, len(record.Uncommitted))})
+		}
+
+		// Roll back failed iterations so the next one starts clean
+		if snapshot != nil && iterationFailed(record) {
+			patchPath := filepath.Join(journal.Dir, fmt.Sprintf("iteration-%d.patch", i))
+			if err := snapshot.Restore(r.WorkDir, patchPath); err != nil {
+				r.emit(Event{Type: EventWarning, Iteration: i, Message: fmt.Sprintf("failed to roll back iteration %d: %v", i, err)})
+			} else {
+				record.RolledBack = true
+				if fileExists(patchPath) {
+					record.Patch = patchPath
+				}
+				r.emit(Event{Type: EventRolledBack, Iteration: i, Message: fmt.Sprintf("Iteration %d failed, rolled back to %s", i, ShortHash(snapshot.Head))})
+			}
+		}
+
+		// Check for completion signal, unless a reviewer reopened a story
+		record.Complete = strings.Contains(output, CompleteSignal)
+		var reviewErr error
+		if !record.RolledBack {
+			record.Rejected, reviewErr = r.reviewStories(ctx, i, prdFile, passing, record.HeadBefore, "HEAD")
+			if len(record.Rejected) > 0 {
+				record.Complete = false
+			}
+		}
+		r.appendRecord(journal, record)
+		if reviewErr != nil {
+			return false, reviewErr
+		}
+		if !record.RolledBack {
+			r.emitPassedStories(i, prdFile, passing)
+		}
+		r.postIteration(ctx, record, stories)
+		r.endIterationSpan(nil)
+
+		if record.Complete {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// appendRecord adds an iteration to the journal and reports it.
+func (r *Runner) appendRecord(journal *Journal, record IterationRecord) {
+	if err := journal.Append(record); err != nil {
+		r.emit(Event{Type: EventWarning, Iteration: record.Iteration, Message: fmt.Sprintf("failed to write run journal: %v", err)})
+	}
+	if span := r.iterationSpan; span != nil {
+		span.Attributes["ralph.exit_code"] = record.ExitCode
+		span.Attributes["ralph.commits"] = len(record.Commits)
+		span.Attributes["ralph.rolled_back"] = record.RolledBack
+		span.Attributes["ralph.complete"] = record.Complete
+		setUsageAttributes(span.Attributes, record.Usage)
+		if len(record.Rejected) > 0 {
+			span.Attributes["ralph.rejected"] = record.Rejected
+		}
+	}
+	r.emit(Event{Type: EventIterationFinished, Iteration: record.Iteration, Stories: record.Stories, Record: &record})
+}
+
+func (r *Runner) emit(event Event) {
+	event.Time = time.Now()
+	event.RunID = r.runID
+	for _, sender := range r.webhooks {
+		sender.Send(event)
+	}
+	if r.OnEvent != nil {
+		r.OnEvent(event)
+	}
+}
+
+// passingStories returns the stories of the PRD that pass.
+func passingStories(prdFile string) map[string]bool {
+	passing := make(map[string]bool)
+	prd, err := LoadPRD(prdFile)
+	if err != nil {
+		return passing
+	}
+	for _, story := range prd.UserStories {
+		if story.Passes {
+			passing[story.ID] = true
+		}
+	}
+	return passing
+}
+
+// emitPassedStories reports the stories that pass now but didn't before.
+func (r *Runner) emitPassedStories(iteration int, prdFile string, before map[string]bool) {
+	prd, err := LoadPRD(prdFile)
+	if err != nil {
+		return
+	}
+	for _, story := range prd.UserStories {
+		if story.Passes && !before[story.ID] {
+			r.emit(Event{Type: EventStoryPassed, Iteration: iteration, Stories: []string{story.ID}, Message: fmt.Sprintf("Story %s passes - %s", story.ID, story.Title)})
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package ralph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// agentFunc adapts a function to the Agent interface.
+type agentFunc func(ctx context.Context, dir string, prompt []byte, stdout, stderr io.Writer) (string, error)
+
+func (f agentFunc) Run(ctx context.Context, dir string, prompt []byte, stdout, stderr io.Writer) (string, error) {
+	return f(ctx, dir, prompt, stdout, stderr)
+}
+
+func setupRunnerRepo(t *testing.T) (string, *Config) {
+	t.Helper()
+	dir := initTestRepo(t)
+	ralphDir := filepath.Join(dir, ".ralph")
+	os.MkdirAll(ralphDir, 0755)
+	os.WriteFile(filepath.Join(ralphDir, "prompt.md"), []byte("Implement the next story.\n"), 0644)
+	prd := &PRD{BranchName: "ralph/feature", UserStories: []UserStory{{ID: "US-1", Title: "First"}}}
+	if err := SavePRD(filepath.Join(ralphDir, "prd.yaml"), prd); err != nil {
+		t.Fatalf("Failed to save PRD: %v", err)
+	}
+
+	return dir, &Config{Tool: "fake", MaxIterations: 3, PromptFile: "prompt.md"}
+}
+
+func TestRunnerRun(t *testing.T) {
+	dir, config := setupRunnerRepo(t)
+
+	calls := 0
+	agent := agentFunc(func(ctx context.Context, agentDir string, prompt []byte, stdout, stderr io.Writer) (string, error) {
+		calls++
+		if string(prompt) != "Implement the next story.\n" {
+			t.Errorf("Unexpected prompt: %q", prompt)
+		}
+		file := fmt.Sprintf("step-%d.txt", calls)
+		os.WriteFile(filepath.Join(agentDir, file), []byte("done\n"), 0644)
+		runGit(agentDir, "add", file)
+		runGit(agentDir, "commit", "-m", "feat: step "+file)
+		if calls == 2 {
+			return "all done " + CompleteSignal, nil
+		}
+		return "more to do", nil
+	})
+
+	var events []EventType
+	runner := &Runner{
+		Config:  config,
+		WorkDir: dir,
+		Agent:   agent,
+		OnEvent: func(e Event) { events = append(events, e.Type) },
+	}
+
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !result.Complete || len(result.Records) != 2 {
+		t.Fatalf("Expected completion after 2 iterations, got %+v", result)
+	}
+	if len(result.Records[0].Commits) != 1 || !result.Records[1].Complete {
+		t.Errorf("Unexpected records: %+v", result.Records)
+	}
+	if fmt.Sprint(result.Records[0].Stories) != "[US-1]" {
+		t.Errorf("Expected the first record for US-1, got %v", result.Records[0].Stories)
+	}
+	if branch, _ := CurrentBranch(dir); branch != "ralph/feature" {
+		t.Errorf("Expected run on ralph/feature, got %s", branch)
+	}
+	if fileExists(filepath.Join(dir, ".ralph", LockFile)) {
+		t.Error("Expected lock to be released")
+	}
+
+	expected := []EventType{
+		EventBranchCreated, EventRunStarted,
+		EventIterationStarted, EventIterationFinished,
+		EventIterationStarted, EventIterationFinished,
+		EventRunFinished,
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("Expected events %v, got %v", expected, events)
+	}
+
+	records, err := LoadJournal(filepath.Join(result.Dir, "journal.jsonl"))
+	if err != nil || len(records) != 2 {
+		t.Errorf("Expected 2 journal records, got %d, %v", len(records), err)
+	}
+}
+
+func TestRunnerRunErrors(t *testing.T) {
+	t.Run("dirty tree", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		os.WriteFile(filepath.Join(dir, "wip.txt"), []byte("wip\n"), 0644)
+		ralphDir := filepath.Join(dir, ".ralph")
+		os.WriteFile(filepath.Join(ralphDir, ".last-branch"), []byte("ralph/previous\n"), 0644)
+		os.WriteFile(filepath.Join(ralphDir, "progress.txt"), []byte("previous progress\n"), 0644)
+
+		runner := &Runner{Config: config, WorkDir: dir, Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+			t.Error("Expected agent not to run")
+			return "", nil
+		})}
+		_, err := runner.Run(context.Background())
+
+		var dirty *DirtyTreeError
+		if !errors.As(err, &dirty) || len(dirty.Files) != 1 || dirty.Files[0] != "wip.txt" {
+			t.Errorf("Expected DirtyTreeError for wip.txt, got %v", err)
+		}
+		if progress := readFile(filepath.Join(ralphDir, "progress.txt")); progress != "previous progress" {
+			t.Errorf("Expected progress.txt left alone, got %q", progress)
+		}
+		if last := readFile(filepath.Join(ralphDir, ".last-branch")); last != "ralph/previous" {
+			t.Errorf("Expected .last-branch left alone, got %q", last)
+		}
+		if fileExists(filepath.Join(ralphDir, "archive")) {
+			t.Error("Expected no archive for a refused run")
+		}
+	})
+
+	t.Run("missing prompt", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		config.PromptFile = "nonexistent.md"
+
+		runner := &Runner{Config: config, WorkDir: dir, Agent: CommandAgent{Command: "echo"}}
+		if _, err := runner.Run(context.Background()); err == nil {
+			t.Error("Expected error when the prompt file doesn't exist")
+		}
+	})
+
+	t.Run("agent switches branch", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		runner := &Runner{Config: config, WorkDir: dir, Agent: agentFunc(func(ctx context.Context, agentDir string, _ []byte, _, _ io.Writer) (string, error) {
+			runGit(agentDir, "checkout", "-q", "main")
+			return "", nil
+		})}
+
+		result, err := runner.Run(context.Background())
+		if err == nil || len(result.Records) != 1 {
+			t.Errorf("Expected branch switch error after 1 iteration, got %v, %+v", err, result)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		dir, config := setupRunnerRepo(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		runner := &Runner{Config: config, WorkDir: dir, Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+			cancel()
+			return "", context.Canceled
+		})}
+
+		result, err := runner.Run(ctx)
+		if !errors.Is(err, context.Canceled) || len(result.Records) != 1 {
+			t.Errorf("Expected cancellation after 1 iteration, got %v, %+v", err, result)
+		}
+		if fileExists(filepath.Join(dir, ".ralph", LockFile)) {
+			t.Error("Expected lock to be released")
+		}
+	})
+}
+
+func TestRunnerHeldLock(t *testing.T) {
+	dir, config := setupRunnerRepo(t)
+	lockPath := filepath.Join(dir, ".ralph", LockFile)
+	lock, _, err := AcquireLock(lockPath)
+	if err != nil {
+		t.Fatalf("AcquireLock failed: %v", err)
+	}
+	defer lock.Release()
+
+	runner := &Runner{Config: config, WorkDir: dir, Lock: lock, Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+		return CompleteSignal, nil
+	})}
+	if _, err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("Expected run under the caller's lock, got %v", err)
+	}
+	if holder, err := ReadLock(lockPath); err != nil || holder.PID != lock.PID {
+		t.Errorf("Expected the caller to keep the lock, got %v, %v", holder, err)
+	}
+}
+
+func TestCommandAgent(t *testing.T) {
+	output, err := CommandAgent{Command: "cat"}.Run(context.Background(), "", []byte("hello"), io.Discard, io.Discard)
+	if err != nil || output != "hello" {
+		t.Errorf("Expected prompt echoed back, got %q, %v", output, err)
+	}
+
+	_, err = CommandAgent{Command: "nonexistent-command-12345"}.Run(context.Background(), "", nil, io.Discard, io.Discard)
+	if err == nil || exitCode(err) != -1 {
+		t.Errorf("Expected error for missing command, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package ralph
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// SocketFile is the name of the Unix socket a run listens on in the ralph
+// directory for control requests.
+const SocketFile = "ralph.sock"
+
+// Control commands accepted by ControlRequest.
+const (
+	ControlPause              = "pause"
+	ControlResume             = "resume"
+	ControlSkip               = "skip"
+	ControlStopAfterIteration = "stop-after-iteration"
+	ControlStatus             = "status"
+)
+
+// ControlRequest is sent as a single JSON line over the control socket.
+type ControlRequest struct {
+	Command string `json:"command"`
+	Story   string `json:"story,omitempty"`
+}
+
+// ControlResponse answers a ControlRequest with the state after applying it.
+type ControlResponse struct {
+	State ControlState `json:"state"`
+	Error string       `json:"error,omitempty"`
+}
+
+// Apply performs a control request.
+func (c *Control) Apply(req ControlRequest) error {
+	switch req.Command {
+	case ControlPause:
+		c.Pause()
+	case ControlResume:
+		c.Resume()
+	case ControlSkip:
+		if req.Story == "" {
+			return errors.New("skip requires a story id")
+		}
+		c.Skip(req.Story)
+	case ControlStopAfterIteration:
+		c.StopAfterIteration()
+	case ControlStatus:
+	default:
+		return fmt.Errorf("unknown control command %q", req.Command)
+	}
+	return nil
+}
+
+// ServeControl applies the requests received on a Unix socket at path to c
+// until the returned function is called. A file left at path by an earlier
+// run is replaced.
+func ServeControl(path string, c *Control) (func() error, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveControlConn(conn, c)
+		}
+	}()
+
+	return listener.Close, nil
+}
+
+func serveControlConn(conn net.Conn, c *Control) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	var req ControlRequest
+	var resp ControlResponse
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &req)
+	}
+	if err == nil {
+		err = c.Apply(req)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	resp.State = c.State()
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// SendControl sends a request to the run listening on the socket at path and
+// returns its control state after the request.
+func SendControl(ctx context.Context, path string, req ControlRequest) (ControlState, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return ControlState{}, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return ControlState{}, err
+	}
+	var resp ControlResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return ControlState{}, err
+	}
+	if resp.Error != "" {
+		return resp.State, errors.New(resp.Error)
+	}
+	return resp.State, nil
+}
@@ -0,0 +1,73 @@
+package ralph
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServeControl(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SocketFile)
+	os.WriteFile(path, []byte("left behind"), 0644)
+
+	control := &Control{}
+	closeControl, err := ServeControl(path, control)
+	if err != nil {
+		t.Fatalf("ServeControl failed: %v", err)
+	}
+	defer closeControl()
+
+	ctx := context.Background()
+	if state, err := SendControl(ctx, path, ControlRequest{Command: ControlPause}); err != nil || !state.Paused {
+		t.Errorf("Expected pause, got %+v, %v", state, err)
+	}
+	if state, err := SendControl(ctx, path, ControlRequest{Command: ControlSkip, Story: "US-2"}); err != nil || strings.Join(state.Skipped, ",") != "US-2" {
+		t.Errorf("Expected US-2 skipped, got %+v, %v", state, err)
+	}
+	if state, err := SendControl(ctx, path, ControlRequest{Command: ControlResume}); err != nil || state.Paused {
+		t.Errorf("Expected resume, got %+v, %v", state, err)
+	}
+	if state, err := SendControl(ctx, path, ControlRequest{Command: ControlStopAfterIteration}); err != nil || !state.StopAfterIteration {
+		t.Errorf("Expected stop after iteration, got %+v, %v", state, err)
+	}
+
+	if _, err := SendControl(ctx, path, ControlRequest{Command: ControlSkip}); err == nil || !strings.Contains(err.Error(), "requires a story id") {
+		t.Errorf("Expected an error for skip without story, got %v", err)
+	}
+	if _, err := SendControl(ctx, path, ControlRequest{Command: "reboot"}); err == nil || !strings.Contains(err.Error(), "unknown control command") {
+		t.Errorf("Expected an error for an unknown command, got %v", err)
+	}
+
+	closeControl()
+	if _, err := SendControl(ctx, path, ControlRequest{Command: ControlStatus}); err == nil {
+		t.Error("Expected an error once the socket is closed")
+	}
+}
+
+func TestRunnerControlSocket(t *testing.T) {
+	dir, config := setupRunnerRepo(t)
+	socket := filepath.Join(dir, ".ralph", SocketFile)
+
+	runner := &Runner{
+		Config:        config,
+		WorkDir:       dir,
+		ControlSocket: socket,
+		Agent: agentFunc(func(ctx context.Context, _ string, _ []byte, _, _ io.Writer) (string, error) {
+			if _, err := SendControl(ctx, socket, ControlRequest{Command: ControlStopAfterIteration}); err != nil {
+				t.Errorf("SendControl failed: %v", err)
+			}
+			return "", nil
+		}),
+	}
+
+	result, err := runner.Run(context.Background())
+	if err != nil || result.Reason != StopRequested || len(result.Records) != 1 {
+		t.Errorf("Expected stop after 1 iteration, got %v, %+v", err, result)
+	}
+	if fileExists(socket) {
+		t.Error("Expected the socket to be removed after the run")
+	}
+}
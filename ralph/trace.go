@@ -0,0 +1,75 @@
+package ralph
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Span is a timed step of a run for tracing: the run itself, its iterations,
+// and the hooks, tool executions and approval gates within them. The spans of
+// a run share TraceID and point to their parent through ParentID.
+type Span struct {
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_id,omitempty"`
+	Name       string         `json:"name"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// startSpan starts a span under parent, or a root span when parent is nil.
+func (r *Runner) startSpan(name string, parent *Span, attributes map[string]any) *Span {
+	span := &Span{TraceID: r.traceID, SpanID: randomHex(8), Name: name, Start: time.Now(), Attributes: attributes}
+	if span.Attributes == nil {
+		span.Attributes = map[string]any{}
+	}
+	if parent != nil {
+		span.ParentID = parent.SpanID
+	}
+	return span
+}
+
+// endSpan ends a span, unless it was ended already, and passes it to OnSpan.
+func (r *Runner) endSpan(span *Span, err error) {
+	if span.End.IsZero() {
+		span.End = time.Now()
+	}
+	if err != nil {
+		span.Error = err.Error()
+	}
+	if r.OnSpan != nil {
+		r.OnSpan(*span)
+	}
+}
+
+// startIterationSpan ends the span of the previous iteration, if still open,
+// and starts the one for iteration.
+func (r *Runner) startIterationSpan(iteration int, stories []string) {
+	r.endIterationSpan(nil)
+	r.iterationSpan = r.startSpan("iteration", r.runSpan, map[string]any{"ralph.iteration": iteration, "ralph.story_ids": stories})
+}
+
+func (r *Runner) endIterationSpan(err error) {
+	if r.iterationSpan != nil {
+		r.endSpan(r.iterationSpan, err)
+		r.iterationSpan = nil
+	}
+}
+
+// parentSpan is the span hooks and gates belong to: the current iteration,
+// or the run between iterations.
+func (r *Runner) parentSpan() *Span {
+	if r.iterationSpan != nil {
+		return r.iterationSpan
+	}
+	return r.runSpan
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,71 @@
+package ralph
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestRunnerSpans(t *testing.T) {
+	dir, config := setupRunnerRepo(t)
+	config.Hooks = Hooks{PreRun: []string{"true"}, PreIteration: []string{"true"}}
+
+	calls := 0
+	var spans []Span
+	runner := &Runner{
+		Config:  config,
+		WorkDir: dir,
+		Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+			calls++
+			if calls == 1 {
+				return "", errors.New("exit status 2")
+			}
+			return CompleteSignal, nil
+		}),
+		OnSpan: func(s Span) { spans = append(spans, s) },
+	}
+
+	result, err := runner.Run(context.Background())
+	if err != nil || !result.Complete {
+		t.Fatalf("Expected completion, got %v, %+v", err, result)
+	}
+
+	var names []string
+	byID := map[string]Span{}
+	for _, span := range spans {
+		names = append(names, span.Name)
+		byID[span.SpanID] = span
+		if span.TraceID != spans[0].TraceID || len(span.TraceID) != 32 || len(span.SpanID) != 16 {
+			t.Errorf("Unexpected IDs in %+v", span)
+		}
+		if span.End.Before(span.Start) {
+			t.Errorf("Span %s ends before it starts", span.Name)
+		}
+	}
+	want := []string{"hook pre_run", "hook pre_iteration", "tool fake", "iteration", "hook pre_iteration", "tool fake", "iteration", "run"}
+	if len(names) != len(want) {
+		t.Fatalf("Expected spans %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("Expected spans %v, got %v", want, names)
+		}
+	}
+
+	run := spans[len(spans)-1]
+	if run.ParentID != "" || run.Attributes["ralph.run_id"] != result.RunID || run.Attributes["ralph.reason"] != "complete" {
+		t.Errorf("Unexpected run span: %+v", run)
+	}
+	if spans[0].ParentID != run.SpanID {
+		t.Errorf("Expected pre_run under the run, got parent %q", spans[0].ParentID)
+	}
+	iteration := spans[3]
+	if iteration.ParentID != run.SpanID || iteration.Attributes["ralph.iteration"] != 1 || iteration.Attributes["ralph.exit_code"] != -1 {
+		t.Errorf("Unexpected iteration span: %+v", iteration)
+	}
+	tool := spans[2]
+	if tool.ParentID != iteration.SpanID || tool.Error != "exit status 2" || byID[spans[1].ParentID].Name != "iteration" {
+		t.Errorf("Expected the tool and hook under the iteration, got %+v", tool)
+	}
+}
@@ -0,0 +1,79 @@
+package ralph
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Usage counts the tokens an agent consumed in an iteration, as reported by
+// the tool itself. Tools that don't print their usage, like claude with the
+// default text output or copilot, leave it unknown.
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// Total returns every token consumed, cached input included.
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// Add adds other to u.
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheCreationInputTokens += other.CacheCreationInputTokens
+	u.CacheReadInputTokens += other.CacheReadInputTokens
+}
+
+// ParseUsage finds the token usage in an agent's output: the result object
+// claude prints with --output-format json, or the final result event of
+// --output-format stream-json. It reports false when the output has none.
+func ParseUsage(output string) (Usage, bool) {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		var result struct {
+			Type  string `json:"type"`
+			Usage *Usage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(line), &result); err != nil || result.Type != "result" || result.Usage == nil {
+			continue
+		}
+		return *result.Usage, true
+	}
+	return Usage{}, false
+}
+
+// sumUsage totals the usage of records, nil when none of them reported any.
+func sumUsage(records []IterationRecord) *Usage {
+	var total *Usage
+	for _, record := range records {
+		if record.Usage == nil {
+			continue
+		}
+		if total == nil {
+			total = &Usage{}
+		}
+		total.Add(*record.Usage)
+	}
+	return total
+}
+
+// setUsageAttributes adds token counts to span attributes.
+func setUsageAttributes(attributes map[string]any, usage *Usage) {
+	if usage == nil {
+		return
+	}
+	attributes["ralph.tokens.input"] = usage.InputTokens
+	attributes["ralph.tokens.output"] = usage.OutputTokens
+	attributes["ralph.tokens.cache_creation"] = usage.CacheCreationInputTokens
+	attributes["ralph.tokens.cache_read"] = usage.CacheReadInputTokens
+	attributes["ralph.tokens.total"] = usage.Total()
+}
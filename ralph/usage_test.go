@@ -0,0 +1,80 @@
+package ralph
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func TestParseUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		usage  Usage
+		ok     bool
+	}{
+		{"text output", "Implemented US-1.\n<promise>COMPLETE</promise>\n", Usage{}, false},
+		{"json", `{"type":"result","subtype":"success","result":"Done <promise>COMPLETE</promise>","usage":{"input_tokens":12,"cache_creation_input_tokens":300,"cache_read_input_tokens":4000,"output_tokens":250}}` + "\n",
+			Usage{InputTokens: 12, OutputTokens: 250, CacheCreationInputTokens: 300, CacheReadInputTokens: 4000}, true},
+		{"stream json", `{"type":"system","subtype":"init"}
+{"type":"assistant","message":{"usage":{"input_tokens":1,"output_tokens":1}}}
+{"type":"result","subtype":"success","usage":{"input_tokens":20,"output_tokens":80}}
+`, Usage{InputTokens: 20, OutputTokens: 80}, true},
+		{"result without usage", `{"type":"result","result":"Done"}`, Usage{}, false},
+		{"invalid json", `{"type":"result","usage":`, Usage{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage, ok := ParseUsage(tt.output)
+			if ok != tt.ok || usage != tt.usage {
+				t.Errorf("Expected %+v, %v, got %+v, %v", tt.usage, tt.ok, usage, ok)
+			}
+		})
+	}
+}
+
+func TestRunnerUsage(t *testing.T) {
+	dir, config := setupRunnerRepo(t)
+
+	calls := 0
+	var spans []Span
+	runner := &Runner{
+		Config:  config,
+		WorkDir: dir,
+		Agent: agentFunc(func(context.Context, string, []byte, io.Writer, io.Writer) (string, error) {
+			calls++
+			if calls == 1 {
+				// Text output doesn't report usage
+				return "working on it", nil
+			}
+			return `{"type":"result","result":"` + CompleteSignal + `","usage":{"input_tokens":100,"output_tokens":40,"cache_read_input_tokens":1000}}`, nil
+		}),
+		OnSpan: func(s Span) { spans = append(spans, s) },
+	}
+
+	result, err := runner.Run(context.Background())
+	if err != nil || !result.Complete || len(result.Records) != 2 {
+		t.Fatalf("Expected completion after 2 iterations, got %v, %+v", err, result)
+	}
+	if result.Records[0].Usage != nil {
+		t.Errorf("Expected no usage for text output, got %+v", result.Records[0].Usage)
+	}
+	want := Usage{InputTokens: 100, OutputTokens: 40, CacheReadInputTokens: 1000}
+	if result.Records[1].Usage == nil || *result.Records[1].Usage != want || result.Usage == nil || *result.Usage != want {
+		t.Errorf("Expected usage %+v, got %+v and %+v", want, result.Records[1].Usage, result.Usage)
+	}
+
+	if run := spans[len(spans)-1]; run.Name != "run" || run.Attributes["ralph.tokens.total"] != 1140 || run.Attributes["ralph.tokens.output"] != 40 {
+		t.Errorf("Expected token totals on the run span, got %+v", run.Attributes)
+	}
+	tools := 0
+	for _, span := range spans {
+		if span.Name == "tool fake" {
+			if _, ok := span.Attributes["ralph.tokens.total"]; ok != (tools == 1) {
+				t.Errorf("Expected tokens only on the second tool span, got %+v", span.Attributes)
+			}
+			tools++
+		}
+	}
+}
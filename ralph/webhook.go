@@ -0,0 +1,268 @@
+package ralph
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Webhook posts run events as JSON to a URL, e.g. a Slack or Teams incoming
+// webhook. URL, Secret and header values expand ${VAR} environment variables
+// so secrets can stay out of the config file.
+type Webhook struct {
+	URL string `yaml:"url"`
+	// Events selects the event types to send, by default run_started,
+	// story_passed and run_finished.
+	Events []EventType `yaml:"events,omitempty"`
+	// Secret signs the body with HMAC-SHA256 in the X-Ralph-Signature header
+	// as sha256=<hex>.
+	Secret string `yaml:"secret,omitempty"`
+	// Template is "slack", "teams" or a text/template for the body, executed
+	// with a WebhookPayload. The payload itself is sent as JSON by default.
+	Template string            `yaml:"template,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	// Retries is how many times a failed delivery is retried, 3 by default.
+	Retries *int `yaml:"retries,omitempty"`
+}
+
+// DefaultWebhookEvents are the events sent to a webhook without events.
+var DefaultWebhookEvents = []EventType{EventRunStarted, EventStoryPassed, EventRunFinished}
+
+// WebhookPayload is the default JSON body of webhook requests and the data
+// of body templates.
+type WebhookPayload struct {
+	Event
+	Branch string `json:"branch,omitempty"`
+	// Text is a one line summary of the event for chat messages.
+	Text string `json:"text"`
+}
+
+// webhookTemplates are the built-in body templates for chat services.
+var webhookTemplates = map[string]string{
+	"slack": `{"text": {{json .Text}}}`,
+	"teams": `{"type": "message", "attachments": [{"contentType": "application/vnd.microsoft.card.adaptive", "content": {"type": "AdaptiveCard", "version": "1.4", "body": [{"type": "TextBlock", "text": {{json .Text}}, "wrap": true}]}}]}`,
+}
+
+var webhookFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// Validate checks the URL, events and template of w.
+func (w Webhook) Validate() error {
+	var errs []error
+	if w.URL == "" {
+		errs = append(errs, errors.New("url is required"))
+	}
+	for _, event := range w.Events {
+		if !contains(eventTypes, event) {
+			errs = append(errs, fmt.Errorf("unknown event '%s'", event))
+		}
+	}
+	if _, err := w.template(); err != nil {
+		errs = append(errs, err)
+	}
+	if w.Retries != nil && *w.Retries < 0 {
+		errs = append(errs, fmt.Errorf("retries must not be negative, got %d", *w.Retries))
+	}
+	return errors.Join(errs...)
+}
+
+func (w Webhook) template() (*template.Template, error) {
+	text := w.Template
+	if builtin, ok := webhookTemplates[text]; ok {
+		text = builtin
+	}
+	if text == "" {
+		return nil, nil
+	}
+	return template.New("webhook").Funcs(webhookFuncs).Option("missingkey=error").Parse(text)
+}
+
+// wants reports whether the webhook subscribes to the event type.
+func (w Webhook) wants(event EventType) bool {
+	events := w.Events
+	if len(events) == 0 {
+		events = DefaultWebhookEvents
+	}
+	return contains(events, event)
+}
+
+// body renders the request body for payload.
+func (w Webhook) body(payload WebhookPayload) ([]byte, error) {
+	tmpl, err := w.template()
+	if err != nil {
+		return nil, err
+	}
+	if tmpl == nil {
+		return json.Marshal(payload)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, payload); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Sign returns the X-Ralph-Signature header value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookSender delivers events to a webhook in the background, so a slow
+// endpoint doesn't hold up the run.
+type webhookSender struct {
+	hook    Webhook
+	branch  string
+	client  *http.Client
+	backoff time.Duration
+	events  chan Event
+	done    chan struct{}
+	errs    []error
+}
+
+func newWebhookSender(hook Webhook, branch string, client *http.Client, backoff time.Duration) *webhookSender {
+	s := &webhookSender{
+		hook:    hook,
+		branch:  branch,
+		client:  client,
+		backoff: backoff,
+		events:  make(chan Event, 100),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *webhookSender) run() {
+	defer close(s.done)
+	for event := range s.events {
+		if err := s.send(event); err != nil {
+			s.errs = append(s.errs, err)
+		}
+	}
+}
+
+// Send queues the event when the webhook subscribes to it.
+func (s *webhookSender) Send(event Event) {
+	if s.hook.wants(event.Type) {
+		s.events <- event
+	}
+}
+
+// Close delivers the queued events and returns the deliveries that failed.
+func (s *webhookSender) Close() []error {
+	close(s.events)
+	<-s.done
+	return s.errs
+}
+
+// send posts the event, retrying network errors, 429 and 5xx responses with
+// exponential backoff.
+func (s *webhookSender) send(event Event) error {
+	text := event.Message
+	if text == "" {
+		text = strings.ReplaceAll(string(event.Type), "_", " ")
+		if event.Iteration > 0 {
+			text += fmt.Sprintf(" (iteration %d)", event.Iteration)
+		}
+	}
+	body, err := s.hook.body(WebhookPayload{Event: event, Branch: s.branch, Text: text})
+	if err != nil {
+		return fmt.Errorf("webhook %s: rendering %s: %w", s.hook.URL, event.Type, err)
+	}
+
+	url := os.ExpandEnv(s.hook.URL)
+	retries := 3
+	if s.hook.Retries != nil {
+		retries = *s.hook.Retries
+	}
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(url, event.Type, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= retries {
+			return fmt.Errorf("webhook %s: sending %s: %w", s.hook.URL, event.Type, err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post makes a single delivery attempt and reports whether a failure is worth
+// retrying.
+func (s *webhookSender) post(url string, event EventType, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-ralph")
+	req.Header.Set("X-Ralph-Event", string(event))
+	for name, value := range s.hook.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	if s.hook.Secret != "" {
+		req.Header.Set("X-Ralph-Signature", Sign(os.ExpandEnv(s.hook.Secret), body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// startWebhooks starts a sender per configured webhook.
+func (r *Runner) startWebhooks() {
+	for _, hook := range r.Config.Notifications.Webhooks {
+		r.webhooks = append(r.webhooks, newWebhookSender(hook, r.branch, http.DefaultClient, time.Second))
+	}
+}
+
+// closeWebhooks waits for pending deliveries and reports the failed ones.
+func (r *Runner) closeWebhooks() {
+	webhooks := r.webhooks
+	r.webhooks = nil
+	for _, sender := range webhooks {
+		for _, err := range sender.Close() {
+			r.emit(Event{Type: EventWarning, Message: err.Error()})
+		}
+	}
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package ralph
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookServer records the requests it receives, answering with the
+// statuses in order and 200 once they run out.
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	s := &webhookServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, req)
+		s.bodies = append(s.bodies, string(body))
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestWebhookBody(t *testing.T) {
+	payload := WebhookPayload{
+		Event:  Event{Type: EventStoryPassed, RunID: "20260124-103000", Stories: []string{"US-1"}, Message: `Story US-1 passes - "Login"`},
+		Branch: "ralph/feature",
+		Text:   `Story US-1 passes - "Login"`,
+	}
+
+	tests := []struct {
+		template string
+		check    func(body map[string]any) bool
+	}{
+		{"", func(body map[string]any) bool {
+			return body["type"] == "story_passed" && body["branch"] == "ralph/feature" && body["run_id"] == "20260124-103000"
+		}},
+		{"slack", func(body map[string]any) bool {
+			return body["text"] == `Story US-1 passes - "Login"`
+		}},
+		{"teams", func(body map[string]any) bool {
+			return body["type"] == "message" && strings.Contains(mustJSON(body), `Story US-1 passes - \"Login\"`)
+		}},
+		{`{"content": {{json .Text}}, "run": {{json .RunID}}}`, func(body map[string]any) bool {
+			return body["content"] == payload.Text && body["run"] == "20260124-103000"
+		}},
+	}
+	for _, tt := range tests {
+		data, err := Webhook{URL: "http://example.com", Template: tt.template}.body(payload)
+		if err != nil {
+			t.Fatalf("body(%q) failed: %v", tt.template, err)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatalf("body(%q) is not JSON: %v\n%s", tt.template, err, data)
+		}
+		if !tt.check(body) {
+			t.Errorf("Unexpected body for template %q: %s", tt.template, data)
+		}
+	}
+}
+
+func mustJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func TestWebhookValidate(t *testing.T) {
+	if err := (Webhook{URL: "http://example.com", Template: "slack", Events: []EventType{EventRunFinished}}).Validate(); err != nil {
+		t.Errorf("Expected valid webhook, got: %v", err)
+	}
+
+	err := Webhook{Template: "{{.Text", Events: []EventType{"gate_failed"}, Retries: intPtr(-1)}.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, expected := range []string{"url is required", "unknown event 'gate_failed'", "unclosed action", "retries must not be negative"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to contain %q, got: %v", expected, err)
+		}
+	}
+}
+
+func TestWebhookSender(t *testing.T) {
+	event := Event{Type: EventRunStarted, Message: "Starting Ralph"}
+
+	t.Run("signs and retries server errors", func(t *testing.T) {
+		server := newWebhookServer(t, http.StatusBadGateway, http.StatusTooManyRequests)
+		t.Setenv("TEST_WEBHOOK_SECRET", "s3cret")
+		t.Setenv("TEST_WEBHOOK_TOKEN", "token")
+
+		hook := Webhook{URL: server.URL, Secret: "${TEST_WEBHOOK_SECRET}", Headers: map[string]string{"Authorization": "Bearer ${TEST_WEBHOOK_TOKEN}"}}
+		sender := newWebhookSender(hook, "ralph/feature", server.Client(), time.Millisecond)
+		sender.Send(event)
+		if errs := sender.Close(); len(errs) != 0 {
+			t.Fatalf("Expected delivery to succeed, got %v", errs)
+		}
+
+		if len(server.requests) != 3 {
+			t.Fatalf("Expected 2 retries, got %d requests", len(server.requests))
+		}
+		req, body := server.requests[2], server.bodies[2]
+		if req.Header.Get("X-Ralph-Signature") != Sign("s3cret", []byte(body)) {
+			t.Errorf("Unexpected signature %s", req.Header.Get("X-Ralph-Signature"))
+		}
+		if req.Header.Get("X-Ralph-Event") != "run_started" || req.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected headers: %v", req.Header)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		server := newWebhookServer(t, 500, 500, 500)
+		sender := newWebhookSender(Webhook{URL: server.URL, Retries: intPtr(1)}, "", server.Client(), time.Millisecond)
+		sender.Send(event)
+		errs := sender.Close()
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "500") || len(server.requests) != 2 {
+			t.Errorf("Expected failure after 1 retry, got %v after %d requests", errs, len(server.requests))
+		}
+	})
+
+	t.Run("doesn't retry client errors", func(t *testing.T) {
+		server := newWebhookServer(t, http.StatusNotFound)
+		sender := newWebhookSender(Webhook{URL: server.URL}, "", server.Client(), time.Millisecond)
+		sender.Send(event)
+		if errs := sender.Close(); len(errs) != 1 || len(server.requests) != 1 {
+			t.Errorf("Expected a single failed request, got %v after %d requests", errs, len(server.requests))
+		}
+	})
+
+	t.Run("filters events", func(t *testing.T) {
+		server := newWebhookServer(t)
+		sender := newWebhookSender(Webhook{URL: server.URL, Events: []EventType{EventRunFinished}}, "", server.Client(), time.Millisecond)
+		sender.Send(event)
+		sender.Send(Event{Type: EventRunFinished})
+		sender.Close()
+		if len(server.requests) != 1 || server.requests[0].Header.Get("X-Ralph-Event") != "run_finished" {
+			t.Errorf("Expected only run_finished, got %d requests", len(server.requests))
+		}
+	})
+}
+
+func TestRunnerWebhooks(t *testing.T) {
+	dir, config := setupRunnerRepo(t)
+	server := newWebhookServer(t)
+	config.Notifications.Webhooks = []Webhook{{URL: server.URL, Template: "slack"}}
+
+	agent := agentFunc(func(ctx context.Context, agentDir string, _ []byte, _, _ io.Writer) (string, error) {
+		prdFile := filepath.Join(agentDir, ".ralph", "prd.yaml")
+		prd, _ := LoadPRD(prdFile)
+		prd.UserStories[0].Passes = true
+		SavePRD(prdFile, prd)
+		return CompleteSignal, nil
+	})
+
+	runner := &Runner{Config: config, WorkDir: dir, Agent: agent}
+	if _, err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var events []string
+	for _, req := range server.requests {
+		events = append(events, req.Header.Get("X-Ralph-Event"))
+	}
+	if strings.Join(events, ",") != "run_started,story_passed,run_finished" {
+		t.Fatalf("Unexpected webhook events: %v", events)
+	}
+	for i, expected := range []string{"Starting Ralph", "Story US-1 passes - First", "Ralph completed all tasks: ralph/feature: 1/1 stories pass"} {
+		if !strings.Contains(server.bodies[i], expected) {
+			t.Errorf("Expected %q in body %s", expected, server.bodies[i])
+		}
+	}
+}
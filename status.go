@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+// runStatus implements `go-ralph status`.
+func runStatus(args []string) {
+	fs := newFlagSet("status", "Show the PRD's story progress, the git branch, whether a run is in progress and the last run.")
+	fs.Parse(args)
+
+	workDir, ralphDir := ralphDirs()
+
+	prd, err := ralph.LoadPRD(filepath.Join(ralphDir, "prd.yaml"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading PRD: %v\n", err)
+		os.Exit(1)
+	}
+
+	passing := 0
+	for _, story := range prd.UserStories {
+		if story.Passes {
+			passing++
+		}
+	}
+
+	fmt.Printf("Project: %s\n", prd.Project)
+	branch := prd.BranchName
+	if current, err := ralph.CurrentBranch(workDir); err == nil && current != prd.BranchName {
+		branch += fmt.Sprintf(" (checked out: %s)", current)
+	}
+	fmt.Printf("Branch:  %s\n", branch)
+	fmt.Printf("Stories: %d of %d passing\n\n", passing, len(prd.UserStories))
+	printStories(prd.UserStories)
+
+	fmt.Println()
+	if lock, err := ralph.ReadLock(filepath.Join(ralphDir, ralph.LockFile)); err == nil {
+		fmt.Printf("Run in progress: %s\n", lock)
+		if state, err := sendControl(ralphDir, ralph.ControlRequest{Command: ralph.ControlStatus}); err == nil {
+			fmt.Printf("Run state: %s\n", describeControl(state))
+		}
+	} else {
+		fmt.Println("Run in progress: no")
+	}
+
+	runIDs := listRuns(ralphDir)
+	if len(runIDs) > 0 {
+		runID := runIDs[len(runIDs)-1]
+		records, _ := ralph.LoadJournal(filepath.Join(ralphDir, "runs", runID, "journal.jsonl"))
+		fmt.Printf("Last run: %s (%d iteration(s))\n", runID, len(records))
+	}
+}
+
+func printStories(stories []ralph.UserStory) {
+	for _, story := range stories {
+		mark := "✗"
+		if story.Passes {
+			mark = "✓"
+		}
+		line := fmt.Sprintf("  %s %-10s P%d  %s", mark, story.ID, story.Priority, story.Title)
+		if len(story.DependsOn) > 0 {
+			line += fmt.Sprintf(" (depends on %s)", strings.Join(story.DependsOn, ", "))
+		}
+		fmt.Println(line)
+	}
+}
+
+// listRuns returns the run IDs under .ralph/runs, oldest first.
+func listRuns(ralphDir string) []string {
+	entries, err := os.ReadDir(filepath.Join(ralphDir, "runs"))
+	if err != nil {
+		return nil
+	}
+
+	var runIDs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			runIDs = append(runIDs, entry.Name())
+		}
+	}
+	sort.Strings(runIDs)
+
+	return runIDs
+}
+
+// runLogs implements `go-ralph logs`.
+func runLogs(args []string) {
+	fs := newFlagSet("logs [flags]", "Show the iteration journal of the last run, or of the run given with --run.")
+	runID := fs.String("run", "", "Run ID to show (defaults to the last run)")
+	list := fs.Bool("list", false, "List the recorded runs")
+	raw := fs.Bool("json", false, "Print the raw journal lines")
+	fs.Parse(args)
+
+	_, ralphDir := ralphDirs()
+	runIDs := listRuns(ralphDir)
+
+	if *list {
+		for _, id := range runIDs {
+			records, _ := ralph.LoadJournal(filepath.Join(ralphDir, "runs", id, "journal.jsonl"))
+			fmt.Printf("%s  %d iteration(s)\n", id, len(records))
+		}
+		return
+	}
+
+	if *runID == "" {
+		if len(runIDs) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: no runs recorded in .ralph/runs\n")
+			os.Exit(1)
+		}
+		*runID = runIDs[len(runIDs)-1]
+	}
+
+	journalFile := filepath.Join(ralphDir, "runs", *runID, "journal.jsonl")
+	if *raw {
+		data, err := os.ReadFile(journalFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading journal: %v\n", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(data)
+		return
+	}
+
+	records, err := ralph.LoadJournal(journalFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading journal: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Run %s\n", *runID)
+	printRunSummary(records)
+}
+
+func printRunSummary(records []ralph.IterationRecord) {
+	if len(records) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Run summary:")
+	for _, r := range records {
+		fmt.Printf("  Iteration %d: %d commit(s), %d file(s) changed (+%d/-%d) in %s\n",
+			r.Iteration, len(r.Commits), len(r.Files), r.Insertions(), r.Deletions(), r.Duration.Round(time.Second))
+		for _, c := range r.Commits {
+			fmt.Printf("    %s %s\n", ralph.ShortHash(c.Hash), c.Subject)
+		}
+		if u := r.Usage; u != nil {
+			fmt.Printf("    %d token(s): %d input, %d output, %d cache write, %d cache read\n",
+				u.Total(), u.InputTokens, u.OutputTokens, u.CacheCreationInputTokens, u.CacheReadInputTokens)
+		}
+		if len(r.Uncommitted) > 0 {
+			fmt.Printf("    ⚠ left %d uncommitted change(s) behind\n", len(r.Uncommitted))
+		}
+		if len(r.Conflicts) > 0 {
+			fmt.Printf("    ⚠ merge conflicts, retrying: %s\n", strings.Join(r.Conflicts, ", "))
+		}
+		if len(r.Rejected) > 0 {
+			fmt.Printf("    ⚠ rejected in review, retrying: %s\n", strings.Join(r.Rejected, ", "))
+		}
+		if r.RolledBack {
+			fmt.Printf("    ↩ rolled back (discarded diff: %s)\n", r.Patch)
+		}
+	}
+}
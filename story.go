@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+// runStory implements `go-ralph story list|show|pass|reset`.
+func runStory(args []string) {
+	fs := newFlagSet("story <list|show|pass|reset> [story id]", "List the PRD's stories, show one, or mark one as passing (pass) or not passing (reset).")
+	fs.Parse(args)
+
+	action := "list"
+	if fs.NArg() > 0 {
+		action = fs.Arg(0)
+	}
+
+	_, ralphDir := ralphDirs()
+	prdFile := filepath.Join(ralphDir, "prd.yaml")
+	prd, err := ralph.LoadPRD(prdFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading PRD: %v\n", err)
+		os.Exit(1)
+	}
+
+	if action == "list" {
+		printStories(prd.UserStories)
+		return
+	}
+
+	if action != "show" && action != "pass" && action != "reset" {
+		fmt.Fprintf(os.Stderr, "Error: unknown story command '%s'\n", action)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if fs.NArg() < 2 {
+		fmt.Fprintf(os.Stderr, "Error: story %s requires a story id\n", action)
+		os.Exit(2)
+	}
+
+	story := findStory(prd, fs.Arg(1))
+	if story == nil {
+		fmt.Fprintf(os.Stderr, "Error: story '%s' not found in PRD\n", fs.Arg(1))
+		os.Exit(1)
+	}
+
+	switch action {
+	case "show":
+		printStory(story)
+	case "pass", "reset":
+		story.Passes = action == "pass"
+		if err := ralph.SetStoryPasses(prdFile, story.ID, story.Passes); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving PRD: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Story %s passes: %v\n", story.ID, story.Passes)
+	}
+}
+
+func findStory(prd *ralph.PRD, id string) *ralph.UserStory {
+	for i := range prd.UserStories {
+		if prd.UserStories[i].ID == id {
+			return &prd.UserStories[i]
+		}
+	}
+	return nil
+}
+
+func printStory(story *ralph.UserStory) {
+	fmt.Printf("%s - %s\n", story.ID, story.Title)
+	fmt.Printf("Priority: %d\n", story.Priority)
+	fmt.Printf("Passes:   %v\n", story.Passes)
+	if len(story.DependsOn) > 0 {
+		fmt.Printf("Depends:  %s\n", strings.Join(story.DependsOn, ", "))
+	}
+	fmt.Printf("\n%s\n", story.Description)
+	if len(story.AcceptanceCriteria) > 0 {
+		fmt.Println("\nAcceptance criteria:")
+		for _, criterion := range story.AcceptanceCriteria {
+			fmt.Printf("  - %s\n", criterion)
+		}
+	}
+	if story.Notes != "" {
+		fmt.Printf("\nNotes:\n%s\n", story.Notes)
+	}
+}
+
+// validatePRD checks the PRD for problems that would stall a run.
+func validatePRD(prd *ralph.PRD) error {
+	var errs []error
+
+	if prd.BranchName == "" {
+		errs = append(errs, errors.New("branchName is required"))
+	}
+	if len(prd.UserStories) == 0 {
+		errs = append(errs, errors.New("userStories is empty"))
+	}
+
+	ids := make(map[string]bool)
+	for i, story := range prd.UserStories {
+		if story.ID == "" {
+			errs = append(errs, fmt.Errorf("story #%d has no id", i+1))
+			continue
+		}
+		if ids[story.ID] {
+			errs = append(errs, fmt.Errorf("duplicate story id '%s'", story.ID))
+		}
+		ids[story.ID] = true
+		if story.Title == "" {
+			errs = append(errs, fmt.Errorf("story %s has no title", story.ID))
+		}
+	}
+
+	for _, story := range prd.UserStories {
+		for _, dep := range story.DependsOn {
+			if !ids[dep] {
+				errs = append(errs, fmt.Errorf("story %s depends on unknown story '%s'", story.ID, dep))
+			}
+		}
+	}
+	if cycle := dependencyCycle(prd); len(cycle) > 0 {
+		errs = append(errs, fmt.Errorf("circular dependency: %s", strings.Join(cycle, " -> ")))
+	}
+
+	return errors.Join(errs...)
+}
+
+// dependencyCycle returns the story IDs forming a dependsOn cycle, if any.
+func dependencyCycle(prd *ralph.PRD) []string {
+	deps := make(map[string][]string)
+	for _, story := range prd.UserStories {
+		deps[story.ID] = story.DependsOn
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		switch state[id] {
+		case visiting:
+			for i, p := range path {
+				if p == id {
+					return append(append([]string{}, path[i:]...), id)
+				}
+			}
+		case done:
+			return nil
+		}
+
+		state[id] = visiting
+		path = append(path, id)
+		for _, dep := range deps[id] {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+		return nil
+	}
+
+	for _, story := range prd.UserStories {
+		if cycle := visit(story.ID); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// runValidate implements `go-ralph validate`.
+func runValidate(args []string) {
+	fs := newFlagSet("validate [flags]", "Validate the effective configuration and the PRD without running the agent.")
+	profile := fs.String("profile", "", "Config profile to validate (overrides RALPH_PROFILE)")
+	fs.Parse(args)
+
+	_, ralphDir := ralphDirs()
+	failed := false
+
+	config, _, err := resolveConfig(ralphDir, *profile, nil)
+	if err == nil {
+		var warnings []string
+		warnings, err = validateConfig(config, ralphDir)
+		for _, warning := range warnings {
+			fmt.Printf("Warning: %s\n", warning)
+		}
+	}
+	if err != nil {
+		fmt.Printf("✗ config:\n%s\n", indent(err.Error()))
+		failed = true
+	} else {
+		fmt.Println("✓ config")
+	}
+
+	prd, err := ralph.LoadPRD(filepath.Join(ralphDir, "prd.yaml"))
+	if err == nil {
+		err = validatePRD(prd)
+	}
+	if err != nil {
+		fmt.Printf("✗ prd.yaml:\n%s\n", indent(err.Error()))
+		failed = true
+	} else {
+		fmt.Println("✓ prd.yaml")
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func indent(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+func TestValidatePRD(t *testing.T) {
+	t.Run("valid PRD", func(t *testing.T) {
+		prd := &ralph.PRD{
+			BranchName: "ralph/feature",
+			UserStories: []ralph.UserStory{
+				{ID: "US-1", Title: "First"},
+				{ID: "US-2", Title: "Second", DependsOn: []string{"US-1"}},
+			},
+		}
+		if err := validatePRD(prd); err != nil {
+			t.Errorf("Expected valid PRD, got: %v", err)
+		}
+	})
+
+	t.Run("invalid PRD", func(t *testing.T) {
+		prd := &ralph.PRD{
+			UserStories: []ralph.UserStory{
+				{ID: "US-1", Title: "First"},
+				{ID: "US-1", Title: "Duplicate"},
+				{ID: "US-2", DependsOn: []string{"US-9"}},
+			},
+		}
+		err := validatePRD(prd)
+		if err == nil {
+			t.Fatal("Expected validation error")
+		}
+		for _, expected := range []string{"branchName is required", "duplicate story id 'US-1'", "story US-2 has no title", "unknown story 'US-9'"} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("Expected error to contain %q, got: %v", expected, err)
+			}
+		}
+	})
+}
+
+func TestDependencyCycle(t *testing.T) {
+	prd := &ralph.PRD{
+		UserStories: []ralph.UserStory{
+			{ID: "US-1"},
+			{ID: "US-2", DependsOn: []string{"US-4"}},
+			{ID: "US-3", DependsOn: []string{"US-2"}},
+			{ID: "US-4", DependsOn: []string{"US-3", "US-1"}},
+		},
+	}
+
+	cycle := dependencyCycle(prd)
+	if strings.Join(cycle, " -> ") != "US-2 -> US-4 -> US-3 -> US-2" {
+		t.Errorf("Unexpected cycle: %v", cycle)
+	}
+
+	prd.UserStories[1].DependsOn = nil
+	if cycle := dependencyCycle(prd); cycle != nil {
+		t.Errorf("Expected no cycle, got %v", cycle)
+	}
+}
+
+func TestFindStory(t *testing.T) {
+	prd := &ralph.PRD{UserStories: []ralph.UserStory{{ID: "US-1"}, {ID: "US-2"}}}
+
+	story := findStory(prd, "US-2")
+	if story == nil {
+		t.Fatal("Expected to find US-2")
+	}
+	story.Passes = true
+	if !prd.UserStories[1].Passes {
+		t.Error("Expected findStory to return a pointer into the PRD")
+	}
+	if findStory(prd, "US-9") != nil {
+		t.Error("Expected nil for unknown story")
+	}
+}
+
+func TestListRuns(t *testing.T) {
+	ralphDir := t.TempDir()
+	if runs := listRuns(ralphDir); len(runs) != 0 {
+		t.Errorf("Expected no runs, got %v", runs)
+	}
+
+	for _, id := range []string{"20260124-103000", "20260101-090000"} {
+		os.MkdirAll(filepath.Join(ralphDir, "runs", id), 0755)
+	}
+
+	runs := listRuns(ralphDir)
+	if len(runs) != 2 || runs[0] != "20260101-090000" {
+		t.Errorf("Expected runs oldest first, got %v", runs)
+	}
+}
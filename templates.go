@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// embeddedTemplates maps template names, relative to templates/, to the
+// templates compiled into go-ralph.
+var embeddedTemplates = map[string]string{
+	"config.yaml":             configTemplate,
+	"claude/prompt.md":        claudePrompt,
+	"copilot/prompt.md":       copilotPrompt,
+	"skills/prd-generator.md": prdGeneratorSkill,
+	"skills/prd-converter.md": prdConverterSkill,
+	"gitignore":               gitignoreTemplate,
+}
+
+// resolveTemplatesDir returns the template directory for init: the
+// --templates flag, or else templates_dir from the config layers of ralphDir
+// (global, repo, local and RALPH_TEMPLATES_DIR). An empty result means only
+// embedded templates are used.
+func resolveTemplatesDir(flagValue, ralphDir string) (string, error) {
+	dir := flagValue
+	if dir == "" {
+		layers, err := configFileLayers(ralphDir)
+		if err != nil {
+			return "", err
+		}
+		values, _ := mergeConfigLayers(append(layers, envConfigLayer()))
+		dir, _ = values["templates_dir"].(string)
+	}
+	if dir == "" {
+		return "", nil
+	}
+
+	if rest, ok := strings.CutPrefix(dir, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, rest)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("templates directory: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("templates directory %s is not a directory", dir)
+	}
+
+	return dir, nil
+}
+
+// readTemplate returns the named template from dir, falling back to the
+// embedded template when dir is empty or doesn't have it.
+func readTemplate(dir, name string) (string, error) {
+	if dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err == nil {
+			return string(data), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	content, ok := embeddedTemplates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown template %s", name)
+	}
+	return content, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadTemplate(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "claude"), 0755)
+	os.WriteFile(filepath.Join(dir, "claude", "prompt.md"), []byte("# House prompt\n"), 0644)
+
+	content, err := readTemplate(dir, "claude/prompt.md")
+	if err != nil || content != "# House prompt\n" {
+		t.Errorf("Expected custom prompt, got %q, %v", content, err)
+	}
+
+	content, err = readTemplate(dir, "skills/prd-generator.md")
+	if err != nil || content != prdGeneratorSkill {
+		t.Errorf("Expected embedded fallback, got %v", err)
+	}
+
+	if _, err := readTemplate("", "skills/unknown.md"); err == nil {
+		t.Error("Expected error for unknown template")
+	}
+}
+
+func TestInitFilesWithTemplatesDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("version: 1\ntool: {{.Tool}}\nprompt_file: {{.PromptFile}}\nmax_iterations: 3\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "skills"), 0755)
+	os.WriteFile(filepath.Join(dir, "skills", "prd-converter.md"), []byte("house converter\n"), 0644)
+
+	files, err := initFiles([]string{"copilot"}, "prompt.md", dir)
+	if err != nil {
+		t.Fatalf("initFiles failed: %v", err)
+	}
+	if files[0].content != "version: 1\ntool: copilot\nprompt_file: prompt.md\nmax_iterations: 3\n" {
+		t.Errorf("Unexpected config:\n%s", files[0].content)
+	}
+	if files[1].content != copilotPrompt {
+		t.Error("Expected embedded copilot prompt")
+	}
+	if files[3].content != "house converter\n" {
+		t.Errorf("Expected custom converter skill, got %q", files[3].content)
+	}
+}
+
+func TestResolveTemplatesDir(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("RALPH_TEMPLATES_DIR", "")
+	ralphDir := filepath.Join(t.TempDir(), ".ralph")
+	os.MkdirAll(ralphDir, 0755)
+
+	if dir, err := resolveTemplatesDir("", ralphDir); err != nil || dir != "" {
+		t.Errorf("Expected no templates dir, got %q, %v", dir, err)
+	}
+
+	orgDir := t.TempDir()
+	os.MkdirAll(filepath.Join(configHome, "go-ralph"), 0755)
+	os.WriteFile(globalConfigPath(), []byte("templates_dir: "+orgDir+"\n"), 0644)
+	if dir, err := resolveTemplatesDir("", ralphDir); err != nil || dir != orgDir {
+		t.Errorf("Expected global templates dir, got %q, %v", dir, err)
+	}
+
+	repoDir := t.TempDir()
+	os.WriteFile(filepath.Join(ralphDir, "config.yaml"), []byte("templates_dir: "+repoDir+"\n"), 0644)
+	if dir, err := resolveTemplatesDir("", ralphDir); err != nil || dir != repoDir {
+		t.Errorf("Expected repo templates dir, got %q, %v", dir, err)
+	}
+
+	localDir := t.TempDir()
+	os.WriteFile(filepath.Join(ralphDir, "config.local.yaml"), []byte("templates_dir: "+localDir+"\n"), 0644)
+	if dir, err := resolveTemplatesDir("", ralphDir); err != nil || dir != localDir {
+		t.Errorf("Expected local templates dir, got %q, %v", dir, err)
+	}
+
+	envDir := t.TempDir()
+	t.Setenv("RALPH_TEMPLATES_DIR", envDir)
+	if dir, err := resolveTemplatesDir("", ralphDir); err != nil || dir != envDir {
+		t.Errorf("Expected env templates dir, got %q, %v", dir, err)
+	}
+
+	flagDir := t.TempDir()
+	if dir, err := resolveTemplatesDir(flagDir, ralphDir); err != nil || dir != flagDir {
+		t.Errorf("Expected flag templates dir, got %q, %v", dir, err)
+	}
+
+	_, err := resolveTemplatesDir(filepath.Join(flagDir, "missing"), ralphDir)
+	if err == nil || !strings.Contains(err.Error(), "templates directory") {
+		t.Errorf("Expected missing directory error, got: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+func testSpans() []ralph.Span {
+	start := time.Unix(1700000000, 0)
+	return []ralph.Span{
+		{TraceID: "0102030405060708090a0b0c0d0e0f10", SpanID: "1111111111111111", ParentID: "2222222222222222", Name: "tool claude",
+			Start: start, End: start.Add(time.Minute), Error: "exit status 1",
+			Attributes: map[string]any{"ralph.exit_code": 1, "ralph.story_ids": []string{"US-001"}, "ralph.tool": "claude"}},
+		{TraceID: "0102030405060708090a0b0c0d0e0f10", SpanID: "2222222222222222", Name: "run",
+			Start: start, End: start.Add(2 * time.Minute), Attributes: map[string]any{"ralph.rolled_back": false}},
+	}
+}
+
+func TestEncodeTrace(t *testing.T) {
+	data, err := encodeTrace(testSpans(), "Test")
+	if err != nil {
+		t.Fatalf("encodeTrace failed: %v", err)
+	}
+
+	var traces otlpTraces
+	if err := json.Unmarshal(data, &traces); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	resource := traces.ResourceSpans[0]
+	if len(resource.Resource.Attributes) != 2 || resource.Resource.Attributes[0].Key != "ralph.project" || *resource.Resource.Attributes[1].Value.StringValue != "go-ralph" {
+		t.Errorf("Unexpected resource: %+v", resource.Resource)
+	}
+
+	spans := resource.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	tool := spans[0]
+	if tool.ParentSpanID != "2222222222222222" || tool.StartTimeUnixNano != "1700000000000000000" || tool.EndTimeUnixNano != "1700000060000000000" {
+		t.Errorf("Unexpected span: %+v", tool)
+	}
+	if tool.Status == nil || tool.Status.Code != otlpStatusError || tool.Status.Message != "exit status 1" || spans[1].Status != nil {
+		t.Errorf("Expected an error status on the tool span only, got %+v and %+v", tool.Status, spans[1].Status)
+	}
+
+	for _, want := range []string{
+		`{"key":"ralph.exit_code","value":{"intValue":"1"}}`,
+		`{"key":"ralph.story_ids","value":{"arrayValue":{"values":[{"stringValue":"US-001"}]}}}`,
+		`{"key":"ralph.tool","value":{"stringValue":"claude"}}`,
+		`{"key":"ralph.rolled_back","value":{"boolValue":false}}`,
+		`"kind":1`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s in %s", want, data)
+		}
+	}
+}
+
+func TestExportTrace(t *testing.T) {
+	var paths []string
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, string(body))
+		if r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		}
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	for i := 0; i < 2; i++ {
+		if err := exportTrace(testSpans(), "Test", file, server.URL); err != nil {
+			t.Fatalf("exportTrace failed: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(file)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !json.Valid([]byte(lines[0])) {
+		t.Errorf("Expected one JSON line per run, got:\n%s", data)
+	}
+	if len(paths) != 2 || paths[0] != "/v1/traces" || bodies[0] != lines[0] {
+		t.Errorf("Expected the same export posted to /v1/traces, got %v", paths)
+	}
+
+	if err := exportTrace(testSpans(), "Test", "", server.URL+"/custom"); err != nil || paths[2] != "/custom" {
+		t.Errorf("Expected the endpoint path to be kept, got %v, %v", paths, err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	if err := exportTrace(testSpans(), "Test", "", failing.URL); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected an error for a rejected export, got %v", err)
+	}
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+	"golang.org/x/term"
+)
+
+// tuiOutputLines caps the agent output kept for the output pane.
+const tuiOutputLines = 2000
+
+// ansiEscape matches the CSI and OSC escape sequences agents print for
+// colors and titles, which would break the layout.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-_]`)
+
+// tui renders a run full screen: the stories, the streaming agent output and
+// a run summary, with keys to pause, skip the current story or abort. It
+// replaces the banners printed by printEvent.
+type tui struct {
+	config  *ralph.Config
+	prdFile string
+	control *ralph.Control
+	abort   func()
+	out     io.Writer
+
+	// The terminal is handed back while a story waits for approval, with
+	// the key presses going to the approver
+	fd        int
+	raw       *term.State
+	drawMu    sync.Mutex
+	suspended bool
+	approver  *terminalApprover
+	input     chan []byte
+
+	mu        sync.Mutex
+	prd       *ralph.PRD
+	lines     []string
+	partial   string
+	iteration int
+	working   []string
+	attempts  map[string]int
+	tokens    *ralph.Usage
+	started   time.Time
+	paused    bool
+	finished  bool
+	status    string
+	redraw    chan struct{}
+}
+
+func newTUI(config *ralph.Config, ralphDir string, control *ralph.Control, abort func()) *tui {
+	t := &tui{
+		config:   config,
+		prdFile:  filepath.Join(ralphDir, "prd.yaml"),
+		control:  control,
+		abort:    abort,
+		out:      os.Stdout,
+		attempts: map[string]int{},
+		started:  time.Now(),
+		redraw:   make(chan struct{}, 1),
+		fd:       int(os.Stdin.Fd()),
+		input:    make(chan []byte, 16),
+	}
+	t.approver = newTerminalApprover(&chanReader{ch: t.input}, t.out)
+	t.prd, _ = ralph.LoadPRD(t.prdFile)
+	return t
+}
+
+// start switches the terminal to the alternate screen in raw mode and draws
+// until the returned function is called, which restores the terminal.
+func (t *tui) start() (func(), error) {
+	if err := t.enter(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t.drawLoop(done)
+	}()
+	go t.readKeys(os.Stdin)
+
+	return func() {
+		close(done)
+		wg.Wait()
+		t.leave()
+	}, nil
+}
+
+// enter switches to raw mode and the alternate screen.
+func (t *tui) enter() error {
+	state, err := term.MakeRaw(t.fd)
+	if err != nil {
+		return err
+	}
+	t.raw = state
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	return nil
+}
+
+// leave restores the terminal as it was before enter.
+func (t *tui) leave() {
+	fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+	term.Restore(t.fd, t.raw)
+}
+
+// Approve hands the terminal back to ask for approval of a story, then
+// returns to the TUI.
+func (t *tui) Approve(ctx context.Context, req ralph.ApprovalRequest) (ralph.Approval, error) {
+	t.drawMu.Lock()
+	t.suspended = true
+	t.leave()
+	t.drawMu.Unlock()
+
+	approval, err := t.approver.Approve(ctx, req)
+
+	t.drawMu.Lock()
+	defer t.drawMu.Unlock()
+	if enterErr := t.enter(); enterErr != nil && err == nil {
+		err = enterErr
+	}
+	t.suspended = false
+	t.changed()
+	return approval, err
+}
+
+// drawLoop redraws on changes, and every second for the elapsed time.
+func (t *tui) drawLoop(done chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// Don't leave the terminal in raw mode if drawing panics
+	defer func() {
+		if r := recover(); r != nil {
+			t.leave()
+			panic(r)
+		}
+	}()
+
+	for {
+		t.draw()
+
+		select {
+		case <-done:
+			return
+		case <-t.redraw:
+			// Coalesce bursts of output into one frame
+			time.Sleep(50 * time.Millisecond)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *tui) draw() {
+	t.drawMu.Lock()
+	defer t.drawMu.Unlock()
+	if t.suspended {
+		return
+	}
+
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width, height = 100, 30
+	}
+	fmt.Fprint(t.out, "\x1b[H"+strings.Join(t.render(width, height), "\x1b[K\r\n")+"\x1b[K")
+}
+
+func (t *tui) changed() {
+	select {
+	case t.redraw <- struct{}{}:
+	default:
+	}
+}
+
+func (t *tui) readKeys(in io.Reader) {
+	buf := make([]byte, 16)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+
+		t.drawMu.Lock()
+		suspended := t.suspended
+		t.drawMu.Unlock()
+		if suspended {
+			t.input <- bytes.Clone(buf[:n])
+			continue
+		}
+		for _, key := range buf[:n] {
+			t.handleKey(key)
+		}
+	}
+}
+
+// handleKey acts on a key press: p pauses after the current iteration (or
+// resumes), s skips the stories being worked on and q or Ctrl+C aborts.
+func (t *tui) handleKey(key byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.changed()
+
+	switch key {
+	case 'p', 'P':
+		if t.control.State().Paused {
+			t.control.Resume()
+			t.status = "Resuming"
+		} else {
+			t.control.Pause()
+			t.status = "Pausing after this iteration"
+		}
+	case 's', 'S':
+		if len(t.working) == 0 {
+			t.status = "No story to skip"
+			return
+		}
+		for _, id := range t.working {
+			t.control.Skip(id)
+		}
+		t.status = fmt.Sprintf("Skipping %s after this iteration", strings.Join(t.working, ", "))
+	case 'q', 'Q', 3:
+		t.status = "Aborting"
+		t.abort()
+	}
+}
+
+// OnEvent updates the screen from a run event.
+func (t *tui) OnEvent(event ralph.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.changed()
+
+	if prd, err := ralph.LoadPRD(t.prdFile); err == nil {
+		t.prd = prd
+	}
+
+	switch event.Type {
+	case ralph.EventIterationStarted:
+		t.iteration = event.Iteration
+		t.working = event.Stories
+		for _, id := range event.Stories {
+			t.attempts[id]++
+		}
+		label := fmt.Sprintf("Iteration %d", event.Iteration)
+		if len(event.Stories) > 0 {
+			label += " - " + strings.Join(event.Stories, ", ")
+		}
+		t.appendLines("── " + label + " ──")
+	case ralph.EventIterationFinished:
+		t.working = nil
+		if event.Record != nil && event.Record.Usage != nil {
+			if t.tokens == nil {
+				t.tokens = &ralph.Usage{}
+			}
+			t.tokens.Add(*event.Record.Usage)
+		}
+	case ralph.EventPaused:
+		t.paused = true
+		t.status = event.Message + ", press p to resume"
+	case ralph.EventResumed:
+		t.paused = false
+		t.status = ""
+	case ralph.EventRunFinished:
+		t.finished = true
+		t.working = nil
+		t.status = event.Message
+	case ralph.EventWarning:
+		t.appendLines("Warning: " + event.Message)
+	default:
+		if event.Message != "" {
+			t.appendLines("» " + event.Message)
+		}
+	}
+}
+
+// Write receives the agent output.
+func (t *tui) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.changed()
+
+	text := t.partial + string(p)
+	lines := strings.Split(text, "\n")
+	t.partial = lines[len(lines)-1]
+	t.appendLines(lines[:len(lines)-1]...)
+	return len(p), nil
+}
+
+func (t *tui) appendLines(lines ...string) {
+	for _, line := range lines {
+		t.lines = append(t.lines, sanitizeLine(line))
+	}
+	if len(t.lines) > tuiOutputLines {
+		t.lines = t.lines[len(t.lines)-tuiOutputLines:]
+	}
+}
+
+// sanitizeLine drops escape sequences and control characters, keeping what
+// follows the last carriage return as a terminal would.
+func sanitizeLine(line string) string {
+	line = ansiEscape.ReplaceAllString(line, "")
+	if i := strings.LastIndex(strings.TrimRight(line, "\r"), "\r"); i >= 0 {
+		line = line[i+1:]
+	}
+	line = strings.ReplaceAll(line, "\t", "    ")
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, line)
+}
+
+// render lays out the screen as height lines of at most width runes.
+func (t *tui) render(width, height int) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	width, height = max(width, 0), max(height, 0)
+
+	control := t.control.State()
+	skipped := map[string]bool{}
+	for _, id := range control.Skipped {
+		skipped[id] = true
+	}
+
+	var project, branch string
+	var stories []ralph.UserStory
+	passed := 0
+	if t.prd != nil {
+		project, branch, stories = t.prd.Project, t.prd.BranchName, t.prd.UserStories
+		for _, story := range stories {
+			if story.Passes {
+				passed++
+			}
+		}
+	}
+
+	state := "running"
+	switch {
+	case t.finished:
+		state = "finished"
+	case t.paused:
+		state = "paused"
+	case control.StopAfterIteration:
+		state = "stopping"
+	case control.Paused:
+		state = "pausing"
+	}
+
+	title := " go-ralph"
+	for _, part := range []string{project, branch} {
+		if part != "" {
+			title += " · " + part
+		}
+	}
+	summary := fmt.Sprintf(" Iteration %d/%d · Elapsed %s · %d/%d stories pass · %s",
+		t.iteration, t.config.MaxIterations, time.Since(t.started).Round(time.Second), passed, len(stories), t.config.Tool)
+	if t.tokens != nil {
+		summary += " · " + formatTokens(t.tokens.Total()) + " tokens"
+	}
+	screen := []string{
+		fit(fit(title, width-len(state)-3)+" ["+state+"]", width),
+		fit(summary, width),
+		strings.Repeat("─", width),
+	}
+
+	// Stories on the left, the tail of the output on the right
+	body := max(height-len(screen)-2, 0)
+	left := min(48, width/3)
+	right := width - left - 3
+	working := map[string]bool{}
+	for _, id := range t.working {
+		working[id] = true
+	}
+	output := t.lines
+	if t.partial != "" {
+		output = append(output[:len(output):len(output)], sanitizeLine(t.partial))
+	}
+	if len(output) > body {
+		output = output[len(output)-body:]
+	}
+
+	for row := 0; row < body; row++ {
+		var story, line string
+		if row < len(stories) {
+			s := stories[row]
+			mark := "·"
+			switch {
+			case s.Passes:
+				mark = "✓"
+			case working[s.ID]:
+				mark = "▶"
+			case skipped[s.ID]:
+				mark = "–"
+			}
+			attempts := ""
+			if n := t.attempts[s.ID]; n > 0 {
+				attempts = fmt.Sprintf(" ×%d", n)
+			}
+			story = fit(fmt.Sprintf(" %s %s %s", mark, s.ID, s.Title), left-utf8.RuneCountInString(attempts)) + attempts
+		}
+		if row < len(output) {
+			line = output[row]
+		}
+		screen = append(screen, fit(pad(story, left)+" │ "+fit(line, right), width))
+	}
+
+	keys := " p pause · s skip story · q abort"
+	if control.Paused || t.paused {
+		keys = " p resume · s skip story · q abort"
+	}
+	screen = append(screen, strings.Repeat("─", width))
+	if t.status != "" {
+		keys += "   " + t.status
+	}
+	screen = append(screen, fit(keys, width))
+
+	// Terminals too small for the frame get its top lines
+	if len(screen) > height {
+		screen = screen[:height]
+	}
+	return screen
+}
+
+// formatTokens abbreviates a token count, e.g. 1234567 as 1.2M.
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
+// fit truncates s to width runes, marking the cut with an ellipsis.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// pad truncates or pads s to exactly width runes.
+func pad(s string, width int) string {
+	s = fit(s, width)
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jlucaspains/go-ralph/ralph"
+)
+
+func newTestTUI(t *testing.T) (*tui, *bool) {
+	t.Helper()
+	aborted := false
+	config := &ralph.Config{Tool: "claude", MaxIterations: 10}
+	return newTUI(config, setupDashboardDir(t), &ralph.Control{}, func() { aborted = true }), &aborted
+}
+
+func TestTUIRender(t *testing.T) {
+	screen, _ := newTestTUI(t)
+	screen.OnEvent(ralph.Event{Type: ralph.EventIterationStarted, Iteration: 1, Stories: []string{"US-002"}})
+	screen.OnEvent(ralph.Event{Type: ralph.EventIterationFinished, Iteration: 1, Record: &ralph.IterationRecord{Usage: &ralph.Usage{InputTokens: 900, OutputTokens: 300, CacheReadInputTokens: 11000}}})
+	screen.OnEvent(ralph.Event{Type: ralph.EventIterationStarted, Iteration: 2, Stories: []string{"US-002"}})
+	screen.Write([]byte("\x1b[32mworking\x1b[0m on it\npartial"))
+
+	lines := screen.render(80, 12)
+	if len(lines) != 12 {
+		t.Fatalf("Expected 12 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if n := utf8.RuneCountInString(line); n > 80 {
+			t.Errorf("Line %d is %d runes wide: %q", i, n, line)
+		}
+	}
+
+	text := strings.Join(lines, "\n")
+	for _, want := range []string{"go-ralph · Test · ralph/feature", "[running]", "Iteration 2/10", "1/2 stories pass", "claude", "12.2k tokens",
+		"✓ US-001 Done", "▶ US-002 Next", "×2", "── Iteration 2 - US-002 ──", "working on it", "partial", "p pause"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected screen to contain %q, got:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "\x1b") {
+		t.Errorf("Expected escape sequences to be stripped, got:\n%s", text)
+	}
+}
+
+func TestTUIRenderTail(t *testing.T) {
+	screen, _ := newTestTUI(t)
+	for i := 0; i < 50; i++ {
+		screen.Write([]byte(strings.Repeat("x", i%5) + "line\n"))
+	}
+	screen.Write([]byte("last\n"))
+
+	lines := screen.render(60, 8)
+	if body := lines[len(lines)-3]; !strings.HasSuffix(body, "│ last") {
+		t.Errorf("Expected the last output line at the bottom of the pane, got %q", body)
+	}
+}
+
+func TestTUIRenderSmall(t *testing.T) {
+	screen, _ := newTestTUI(t)
+	screen.Write([]byte("output\n"))
+
+	for _, size := range [][2]int{{0, 0}, {80, 1}, {80, 4}, {2, 5}, {3, 20}, {-1, -1}} {
+		lines := screen.render(size[0], size[1])
+		if len(lines) > max(size[1], 0) {
+			t.Errorf("Expected at most %d lines for %v, got %d", size[1], size, len(lines))
+		}
+		for i, line := range lines {
+			if n := utf8.RuneCountInString(line); n > max(size[0], 0) {
+				t.Errorf("Line %d is %d runes wide for %v: %q", i, n, size, line)
+			}
+		}
+	}
+}
+
+func TestTUIKeys(t *testing.T) {
+	screen, aborted := newTestTUI(t)
+
+	screen.handleKey('s')
+	if state := screen.control.State(); len(state.Skipped) != 0 {
+		t.Errorf("Expected nothing skipped between iterations, got %v", state.Skipped)
+	}
+
+	screen.OnEvent(ralph.Event{Type: ralph.EventIterationStarted, Iteration: 1, Stories: []string{"US-002"}})
+	screen.handleKey('s')
+	if state := screen.control.State(); !slices.Equal(state.Skipped, []string{"US-002"}) {
+		t.Errorf("Expected US-002 to be skipped, got %v", state.Skipped)
+	}
+
+	screen.handleKey('p')
+	if !screen.control.State().Paused {
+		t.Error("Expected p to pause")
+	}
+	if text := strings.Join(screen.render(80, 10), "\n"); !strings.Contains(text, "[pausing]") || !strings.Contains(text, "p resume") {
+		t.Errorf("Expected the screen to show the pending pause, got:\n%s", text)
+	}
+	screen.handleKey('p')
+	if screen.control.State().Paused {
+		t.Error("Expected a second p to resume")
+	}
+
+	screen.handleKey('q')
+	if !*aborted {
+		t.Error("Expected q to abort the run")
+	}
+}
+
+func TestSanitizeLine(t *testing.T) {
+	tests := map[string]string{
+		"plain":                      "plain",
+		"\x1b[1;31mred\x1b[0m":       "red",
+		"\x1b]0;title\x07text":       "text",
+		"10%\r50%\r100%":             "100%",
+		"done\r":                     "done",
+		"a\tb":                       "a    b",
+		"bell\a and backspace\b too": "bell and backspace too",
+	}
+	for input, want := range tests {
+		if got := sanitizeLine(input); got != want {
+			t.Errorf("sanitizeLine(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
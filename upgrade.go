@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateVersion is the version of the embedded prompt and skill
+// templates. Bump it whenever one of them changes.
+const templateVersion = 1
+
+// TemplateManifest records which embedded templates init wrote, so that
+// `init --upgrade` can tell local edits apart from template changes. A copy
+// of each template is kept under .ralph/templates as the merge base.
+type TemplateManifest struct {
+	Version int                       `yaml:"version"`
+	Tools   []string                  `yaml:"tools,omitempty"`
+	Files   map[string]TemplateRecord `yaml:"files"`
+}
+
+type TemplateRecord struct {
+	Template string `yaml:"template"`
+	Hash     string `yaml:"hash"`
+}
+
+func templateManifestPath(workDir string) string {
+	return filepath.Join(workDir, ".ralph", "templates.yaml")
+}
+
+func templateBasePath(workDir, template string) string {
+	return filepath.Join(workDir, ".ralph", "templates", filepath.FromSlash(template))
+}
+
+func templateHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadTemplateManifest reads the manifest, returning an empty one when init
+// ran before templates were recorded.
+func loadTemplateManifest(workDir string) (*TemplateManifest, error) {
+	manifest := &TemplateManifest{Files: map[string]TemplateRecord{}}
+
+	data, err := os.ReadFile(templateManifestPath(workDir))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", templateManifestPath(workDir), err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = map[string]TemplateRecord{}
+	}
+
+	return manifest, nil
+}
+
+// recordTemplates stores the template behind each file as the merge base for
+// the next upgrade and updates the manifest.
+func recordTemplates(workDir string, tools []string, files []initFile) error {
+	manifest, err := loadTemplateManifest(workDir)
+	if err != nil {
+		return err
+	}
+	for _, tool := range tools {
+		if !contains(manifest.Tools, tool) {
+			manifest.Tools = append(manifest.Tools, tool)
+		}
+	}
+
+	for _, file := range files {
+		if file.template == "" {
+			continue
+		}
+		base := templateBasePath(workDir, file.template)
+		if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+			return err
+		}
+		if err := writeFile(base, file.content); err != nil {
+			return err
+		}
+		manifest.Files[filepath.ToSlash(file.path)] = TemplateRecord{Template: file.template, Hash: templateHash(file.content)}
+	}
+	manifest.Version = templateVersion
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+	encoder.Close()
+
+	return os.WriteFile(templateManifestPath(workDir), buf.Bytes(), 0644)
+}
+
+// recordedBase returns the template content file was last written or
+// upgraded from, if it is known.
+func recordedBase(workDir string, manifest *TemplateManifest, file initFile) (string, bool) {
+	record, ok := manifest.Files[filepath.ToSlash(file.path)]
+	if !ok {
+		return "", false
+	}
+	data, err := os.ReadFile(templateBasePath(workDir, record.Template))
+	if err != nil || templateHash(string(data)) != record.Hash {
+		return "", false
+	}
+	return string(data), true
+}
+
+// UpgradeResult describes what upgradeTemplates did to a file.
+type UpgradeResult struct {
+	Path      string
+	Action    string
+	Conflicts int
+}
+
+// upgradeTemplates brings the files init wrote up to date with the embedded
+// templates. Files without local edits are replaced, edited files get a
+// three-way merge of the recorded template, the local file and the new
+// template, with conflict markers where both changed the same lines.
+func upgradeTemplates(workDir string, tools []string, files []initFile, dryRun bool) ([]UpgradeResult, error) {
+	manifest, err := loadTemplateManifest(workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []UpgradeResult
+	var upgraded []initFile
+	for _, file := range files {
+		if file.template == "" {
+			continue
+		}
+		path := filepath.Join(workDir, file.path)
+		result := UpgradeResult{Path: file.path}
+		content := file.content
+
+		base, hasBase := recordedBase(workDir, manifest, file)
+		current, err := os.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+			result.Action = "create"
+		case err != nil:
+			return results, err
+		case string(current) == file.content, hasBase && base == file.content:
+			result.Action = "up to date"
+		case !hasBase:
+			// Without the original template the local edits can't be told
+			// apart from template changes, leave the merge to the user.
+			result.Action = "no base"
+			path += ".new"
+		case string(current) == base:
+			result.Action = "update"
+		default:
+			content, result.Conflicts, err = mergeTemplate(file.path, string(current), base, file.content)
+			if err != nil {
+				return results, err
+			}
+			result.Action = "merge"
+			if result.Conflicts > 0 {
+				result.Action = "conflict"
+			}
+		}
+		results = append(results, result)
+
+		if dryRun {
+			continue
+		}
+		if result.Action != "up to date" {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return results, err
+			}
+			if err := writeFile(path, content); err != nil {
+				return results, err
+			}
+		}
+		if result.Action != "no base" {
+			upgraded = append(upgraded, file)
+		}
+	}
+
+	if dryRun {
+		return results, nil
+	}
+	return results, recordTemplates(workDir, tools, upgraded)
+}
+
+// mergeTemplate three-way merges the changes from base to latest into
+// current with git merge-file, returning the merged content and the number
+// of conflicts.
+func mergeTemplate(name, current, base, latest string) (string, int, error) {
+	tmpDir, err := os.MkdirTemp("", "go-ralph-merge-")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	paths := make([]string, 3)
+	for i, content := range []string{current, base, latest} {
+		paths[i] = filepath.Join(tmpDir, fmt.Sprintf("%d", i))
+		if err := writeFile(paths[i], content); err != nil {
+			return "", 0, err
+		}
+	}
+
+	cmd := exec.Command("git", "merge-file", "-p",
+		"-L", name, "-L", "previous template", "-L", "new template",
+		paths[0], paths[1], paths[2])
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// merge-file exits with the number of conflicts, negative on errors
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		return stdout.String(), exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("git merge-file: %v %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return stdout.String(), 0, nil
+}
+
+// runUpgrade implements `go-ralph init --upgrade`.
+func runUpgrade(tools []string, opts InitOptions) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting current directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	if opts.DryRun {
+		fmt.Printf("Planned template upgrades for tool: %s\n\n", strings.Join(tools, ", "))
+	} else {
+		fmt.Printf("Upgrading templates for tool: %s\n\n", strings.Join(tools, ", "))
+	}
+
+	files, err := initFiles(tools, initPromptFile(filepath.Join(workDir, ".ralph"), tools), opts.TemplatesDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading templates: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.TemplatesDir != "" {
+		fmt.Printf("Using templates from %s\n\n", opts.TemplatesDir)
+	}
+
+	results, err := upgradeTemplates(workDir, tools, files, opts.DryRun)
+	conflicts := false
+	for _, result := range results {
+		switch {
+		case opts.DryRun:
+			fmt.Printf("%-10s %s\n", result.Action, result.Path)
+		case result.Action == "up to date":
+			fmt.Printf("  %s is up to date\n", result.Path)
+		case result.Action == "no base":
+			fmt.Printf("! %s has local changes and no recorded template, wrote the new template to %s.new\n", result.Path, result.Path)
+		case result.Action == "conflict":
+			fmt.Printf("✗ Merged %s with %d conflict(s), resolve the conflict markers\n", result.Path, result.Conflicts)
+			conflicts = true
+		case result.Action == "merge":
+			fmt.Printf("✓ Merged template changes into %s\n", result.Path)
+		default:
+			fmt.Printf("✓ Updated %s\n", result.Path)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if conflicts {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeTemplate(t *testing.T) {
+	base := "# Prompt\n\n1. Read the PRD\n2. Pick a story\n3. Commit\n"
+
+	t.Run("clean merge", func(t *testing.T) {
+		current := "# Prompt\n\n1. Read the PRD\n2. Pick a story\n3. Commit\n\nTeam note: run make lint\n"
+		latest := "# Prompt\n\n1. Read the PRD and progress log\n2. Pick a story\n3. Commit\n"
+
+		merged, conflicts, err := mergeTemplate("prompt.md", current, base, latest)
+		if err != nil {
+			t.Fatalf("mergeTemplate failed: %v", err)
+		}
+		if conflicts != 0 {
+			t.Errorf("Expected no conflicts, got %d", conflicts)
+		}
+		if !strings.Contains(merged, "Read the PRD and progress log") || !strings.Contains(merged, "Team note") {
+			t.Errorf("Expected both changes in merge, got:\n%s", merged)
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		current := "# Prompt\n\n1. Read the PRD\n2. Pick the smallest story\n3. Commit\n"
+		latest := "# Prompt\n\n1. Read the PRD\n2. Pick the highest priority story\n3. Commit\n"
+
+		merged, conflicts, err := mergeTemplate("prompt.md", current, base, latest)
+		if err != nil {
+			t.Fatalf("mergeTemplate failed: %v", err)
+		}
+		if conflicts != 1 {
+			t.Errorf("Expected 1 conflict, got %d", conflicts)
+		}
+		if !strings.Contains(merged, "<<<<<<< prompt.md") || !strings.Contains(merged, ">>>>>>> new template") {
+			t.Errorf("Expected conflict markers, got:\n%s", merged)
+		}
+	})
+}
+
+func TestUpgradeTemplates(t *testing.T) {
+	workDir := t.TempDir()
+	old := []initFile{
+		{filepath.Join(".ralph", "prompt.md"), "line 1\nline 2\nline 3\n", "claude/prompt.md"},
+		{"untouched.md", "original\n", "skills/untouched.md"},
+		{"missing.md", "original\n", "skills/missing.md"},
+	}
+	for _, file := range old {
+		os.MkdirAll(filepath.Join(workDir, filepath.Dir(file.path)), 0755)
+		writeFile(filepath.Join(workDir, file.path), file.content)
+	}
+	if err := recordTemplates(workDir, []string{"claude"}, old); err != nil {
+		t.Fatalf("recordTemplates failed: %v", err)
+	}
+
+	// Local edit to the prompt, one file removed, one never recorded
+	writeFile(filepath.Join(workDir, ".ralph", "prompt.md"), "line 1\nline 2\nline 3\nlocal line\n")
+	os.Remove(filepath.Join(workDir, "missing.md"))
+	writeFile(filepath.Join(workDir, "unrecorded.md"), "edited\n")
+
+	latest := []initFile{
+		{filepath.Join(".ralph", "prompt.md"), "line 1 improved\nline 2\nline 3\n", "claude/prompt.md"},
+		{"untouched.md", "upgraded\n", "skills/untouched.md"},
+		{"missing.md", "upgraded\n", "skills/missing.md"},
+		{"unrecorded.md", "upgraded\n", "skills/unrecorded.md"},
+		{"config.yaml", "tool: claude\n", ""},
+	}
+
+	_, err := upgradeTemplates(workDir, []string{"claude"}, latest, true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if readFile(filepath.Join(workDir, "untouched.md")) != "original" {
+		t.Error("Expected dry run not to write files")
+	}
+
+	results, err := upgradeTemplates(workDir, []string{"claude"}, latest, false)
+	if err != nil {
+		t.Fatalf("upgradeTemplates failed: %v", err)
+	}
+
+	expected := []string{"merge", "update", "create", "no base"}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %+v", len(expected), results)
+	}
+	for i, action := range expected {
+		if results[i].Action != action {
+			t.Errorf("%s: expected %s, got %s", results[i].Path, action, results[i].Action)
+		}
+	}
+
+	if prompt := readFile(filepath.Join(workDir, ".ralph", "prompt.md")); prompt != "line 1 improved\nline 2\nline 3\nlocal line" {
+		t.Errorf("Unexpected merged prompt:\n%s", prompt)
+	}
+	if readFile(filepath.Join(workDir, "untouched.md")) != "upgraded" || readFile(filepath.Join(workDir, "missing.md")) != "upgraded" {
+		t.Error("Expected unedited and missing files to get the new template")
+	}
+	if readFile(filepath.Join(workDir, "unrecorded.md")) != "edited" || readFile(filepath.Join(workDir, "unrecorded.md.new")) != "upgraded" {
+		t.Error("Expected unrecorded file to be kept and the template written next to it")
+	}
+
+	// The new templates are the base for the next upgrade
+	results, err = upgradeTemplates(workDir, []string{"claude"}, latest, false)
+	if err != nil {
+		t.Fatalf("second upgrade failed: %v", err)
+	}
+	for _, result := range results[:3] {
+		if result.Action != "up to date" {
+			t.Errorf("%s: expected no changes, got %s", result.Path, result.Action)
+		}
+	}
+	manifest, _ := loadTemplateManifest(workDir)
+	if manifest.Version != templateVersion || len(manifest.Tools) != 1 || manifest.Files[".ralph/prompt.md"].Hash != templateHash(latest[0].content) {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+}